@@ -3,13 +3,32 @@ package ast
 import (
 	"fmt"
 	"strings"
+
+	"github.com/fj68/vvlang/lexer"
 )
 
 type Expr interface {
 	Inspect() string
+	// Span returns the range of source text the node was parsed from.
+	Span() lexer.Pos
+}
+
+type IntLiteralExpr struct {
+	Pos   lexer.Pos
+	Value int64
+}
+
+func (expr *IntLiteralExpr) Inspect() string {
+	return fmt.Sprintf("IntLiteralExpr{%d}", expr.Value)
+}
+
+func (expr *IntLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
 }
 
+// NumberLiteralExpr is a literal with a fraction, e.g. `0.5`.
 type NumberLiteralExpr struct {
+	Pos   lexer.Pos
 	Value float64
 }
 
@@ -17,7 +36,12 @@ func (expr *NumberLiteralExpr) Inspect() string {
 	return fmt.Sprintf("NumberLiteralExpr{%g}", expr.Value)
 }
 
+func (expr *NumberLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type BoolLiteralExpr struct {
+	Pos   lexer.Pos
 	Value bool
 }
 
@@ -25,7 +49,24 @@ func (expr *BoolLiteralExpr) Inspect() string {
 	return fmt.Sprintf("BoolLiteralExpr{%t}", expr.Value)
 }
 
+func (expr *BoolLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
+type NilLiteralExpr struct {
+	Pos lexer.Pos
+}
+
+func (expr *NilLiteralExpr) Inspect() string {
+	return "NilLiteralExpr{}"
+}
+
+func (expr *NilLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type StringLiteralExpr struct {
+	Pos   lexer.Pos
 	Value string
 }
 
@@ -33,6 +74,10 @@ func (expr *StringLiteralExpr) Inspect() string {
 	return fmt.Sprintf("StringLiteralExpr{%s}", expr.Value)
 }
 
+func (expr *StringLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 // RecordElement represents either a field or a spread in a record literal
 type RecordElement interface {
 	isRecordElement()
@@ -52,6 +97,7 @@ type RecordSpread struct {
 func (s *RecordSpread) isRecordElement() {}
 
 type RecordLiteralExpr struct {
+	Pos      lexer.Pos
 	Elements []RecordElement
 }
 
@@ -68,7 +114,12 @@ func (expr *RecordLiteralExpr) Inspect() string {
 	return fmt.Sprintf("RecordLiteralExpr{%s}", strings.Join(parts, ", "))
 }
 
+func (expr *RecordLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type InterpolatedStringLiteralExpr struct {
+	Pos    lexer.Pos
 	Texts  []string
 	Values []Expr
 }
@@ -85,7 +136,12 @@ func (expr *InterpolatedStringLiteralExpr) Inspect() string {
 	return fmt.Sprintf("InterpolatedStringLiteralExpr{\"%s\"}", b.String())
 }
 
+func (expr *InterpolatedStringLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type FunLiteralExpr struct {
+	Pos  lexer.Pos
 	Name string
 	Args []string
 	Body []Stmt
@@ -99,7 +155,12 @@ func (expr *FunLiteralExpr) Inspect() string {
 	return fmt.Sprintf("FunLiteralExpr{\"%s\", [%s], [%s]}", expr.Name, strings.Join(expr.Args, ", "), strings.Join(body, ", "))
 }
 
+func (expr *FunLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type FunCallExpr struct {
+	Pos  lexer.Pos
 	Fun  Expr
 	Args []Expr
 }
@@ -112,7 +173,12 @@ func (expr *FunCallExpr) Inspect() string {
 	return fmt.Sprintf("FunCallExpr{%s, [%s]}", expr.Fun.Inspect(), strings.Join(args, ", "))
 }
 
+func (expr *FunCallExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type VarRefExpr struct {
+	Pos  lexer.Pos
 	Name string
 }
 
@@ -120,7 +186,12 @@ func (expr *VarRefExpr) Inspect() string {
 	return fmt.Sprintf("VarRefExpr{\"%s\"}", expr.Name)
 }
 
+func (expr *VarRefExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type PrefixExpr struct {
+	Pos   lexer.Pos
 	Op    string
 	Right Expr
 }
@@ -129,7 +200,12 @@ func (expr *PrefixExpr) Inspect() string {
 	return fmt.Sprintf("PrefixExpr{\"%s\", %s}", expr.Op, expr.Right.Inspect())
 }
 
+func (expr *PrefixExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type InfixExpr struct {
+	Pos   lexer.Pos
 	Op    string
 	Left  Expr
 	Right Expr
@@ -139,7 +215,28 @@ func (expr *InfixExpr) Inspect() string {
 	return fmt.Sprintf("InfixExpr{\"%s\", %s, %s}", expr.Op, expr.Left.Inspect(), expr.Right.Inspect())
 }
 
+func (expr *InfixExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
+// LogicalExpr is an `and` / `or` expression whose right side is evaluated only when needed.
+type LogicalExpr struct {
+	Pos   lexer.Pos
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+func (expr *LogicalExpr) Inspect() string {
+	return fmt.Sprintf("LogicalExpr{\"%s\", %s, %s}", expr.Op, expr.Left.Inspect(), expr.Right.Inspect())
+}
+
+func (expr *LogicalExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type ListLiteralExpr struct {
+	Pos      lexer.Pos
 	Elements []Expr
 }
 
@@ -150,7 +247,13 @@ func (expr *ListLiteralExpr) Inspect() string {
 	}
 	return fmt.Sprintf("ListLiteralExpr{[%s]}", strings.Join(elements, ", "))
 }
+
+func (expr *ListLiteralExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type IndexExpr struct {
+	Pos   lexer.Pos
 	Left  Expr
 	Index Expr
 }
@@ -159,7 +262,12 @@ func (expr *IndexExpr) Inspect() string {
 	return fmt.Sprintf("IndexExpr{%s[%s]}", expr.Left.Inspect(), expr.Index.Inspect())
 }
 
+func (expr *IndexExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type SliceExpr struct {
+	Pos   lexer.Pos
 	Left  Expr
 	Start Expr
 	End   Expr
@@ -177,7 +285,12 @@ func (expr *SliceExpr) Inspect() string {
 	return fmt.Sprintf("SliceExpr{%s[%s:%s]}", expr.Left.Inspect(), startStr, endStr)
 }
 
+func (expr *SliceExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
 type SpreadExpr struct {
+	Pos  lexer.Pos
 	Expr Expr
 }
 
@@ -185,11 +298,27 @@ func (expr *SpreadExpr) Inspect() string {
 	return fmt.Sprintf("SpreadExpr{...%s}", expr.Expr.Inspect())
 }
 
+func (expr *SpreadExpr) Span() lexer.Pos {
+	return expr.Pos
+}
+
+// FieldAccessExpr is `r.field`, or `r?.field` if Optional,
+// which is nil instead of an error when r does not have the field,
+// and skips the rest of the chain of accesses and calls when r is nil.
 type FieldAccessExpr struct {
-	Record Expr
-	Field  string
+	Pos      lexer.Pos
+	Record   Expr
+	Field    string
+	Optional bool
 }
 
 func (expr *FieldAccessExpr) Inspect() string {
+	if expr.Optional {
+		return fmt.Sprintf("FieldAccessExpr{%s?.%s}", expr.Record.Inspect(), expr.Field)
+	}
 	return fmt.Sprintf("FieldAccessExpr{%s.%s}", expr.Record.Inspect(), expr.Field)
-}
\ No newline at end of file
+}
+
+func (expr *FieldAccessExpr) Span() lexer.Pos {
+	return expr.Pos
+}
@@ -3,25 +3,42 @@ package ast
 import (
 	"fmt"
 	"strings"
+
+	"github.com/fj68/vvlang/lexer"
 )
 
 type Stmt interface {
 	Inspect() string
+	// Span returns the range of source text the node was parsed from.
+	Span() lexer.Pos
 }
 
-type BreakStmt struct{}
+type BreakStmt struct {
+	Pos lexer.Pos
+}
 
 func (stmt *BreakStmt) Inspect() string {
 	return "BreakStmt"
 }
 
-type ContinueStmt struct{}
+func (stmt *BreakStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+type ContinueStmt struct {
+	Pos lexer.Pos
+}
 
 func (stmt *ContinueStmt) Inspect() string {
 	return "ContinueStmt"
 }
 
+func (stmt *ContinueStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
 type ReturnStmt struct {
+	Pos   lexer.Pos
 	Value Expr
 }
 
@@ -32,7 +49,12 @@ func (stmt *ReturnStmt) Inspect() string {
 	return fmt.Sprintf("ReturnStmt{%s}", stmt.Value.Inspect())
 }
 
+func (stmt *ReturnStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
 type WhileStmt struct {
+	Pos  lexer.Pos
 	Cond Expr
 	Body []Stmt
 }
@@ -45,7 +67,36 @@ func (stmt *WhileStmt) Inspect() string {
 	return fmt.Sprintf("WhileStmt{%s, %s}", stmt.Cond.Inspect(), strings.Join(body, ", "))
 }
 
+func (stmt *WhileStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+// ForStmt iterates Iter, e.g. `for x in xs ... end` or `for i, x in xs ... end`.
+// Key is empty unless both the key and the value are named.
+type ForStmt struct {
+	Pos   lexer.Pos
+	Key   string
+	Value string
+	Iter  Expr
+	Body  []Stmt
+}
+
+func (stmt *ForStmt) Inspect() string {
+	var body []string
+	for _, s := range stmt.Body {
+		body = append(body, s.Inspect())
+	}
+	return fmt.Sprintf("ForStmt{\"%s\", \"%s\", %s, %s}", stmt.Key, stmt.Value, stmt.Iter.Inspect(), strings.Join(body, ", "))
+}
+
+func (stmt *ForStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+// IfStmt runs Then if Cond is true, or Else otherwise.
+// `elif` and `else if` clauses are chained as an IfStmt alone in Else.
 type IfStmt struct {
+	Pos  lexer.Pos
 	Cond Expr
 	Then []Stmt
 	Else []Stmt
@@ -63,7 +114,12 @@ func (stmt *IfStmt) Inspect() string {
 	return fmt.Sprintf("IfStmt{%s, %s, %s}", stmt.Cond.Inspect(), strings.Join(thenBody, ", "), strings.Join(elseBody, ", "))
 }
 
+func (stmt *IfStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
 type VarDeclStmt struct {
+	Pos  lexer.Pos
 	Name string
 	Body Expr
 }
@@ -72,6 +128,26 @@ func (stmt *VarDeclStmt) Inspect() string {
 	return fmt.Sprintf("VarDeclStmt{\"%s\", %s}", stmt.Name, stmt.Body.Inspect())
 }
 
+func (stmt *VarDeclStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+// AssignStmt assigns to a field or an element, e.g. `r.x = 1` or `xs[0] = 1`.
+// Target is either *FieldAccessExpr or *IndexExpr.
+type AssignStmt struct {
+	Pos    lexer.Pos
+	Target Expr
+	Value  Expr
+}
+
+func (stmt *AssignStmt) Inspect() string {
+	return fmt.Sprintf("AssignStmt{%s, %s}", stmt.Target.Inspect(), stmt.Value.Inspect())
+}
+
+func (stmt *AssignStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
 type ExprStmt struct {
 	Expr
 }
@@ -79,3 +155,88 @@ type ExprStmt struct {
 func (stmt *ExprStmt) Inspect() string {
 	return stmt.Expr.Inspect()
 }
+
+// MatchStmt runs the body of the first case matching Subject, or Else if none matches.
+type MatchStmt struct {
+	Pos     lexer.Pos
+	Subject Expr
+	Cases   []*MatchCase
+	Else    []Stmt
+}
+
+func (stmt *MatchStmt) Inspect() string {
+	var cases []string
+	for _, c := range stmt.Cases {
+		cases = append(cases, c.Inspect())
+	}
+	var elseBody []string
+	for _, s := range stmt.Else {
+		elseBody = append(elseBody, s.Inspect())
+	}
+	return fmt.Sprintf("MatchStmt{%s, [%s], [%s]}", stmt.Subject.Inspect(), strings.Join(cases, ", "), strings.Join(elseBody, ", "))
+}
+
+func (stmt *MatchStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+// MatchCase is a `case` arm, which matches if any of Patterns matches.
+// Patterns are literals, `_`, names to bind, and list and record literals of patterns,
+// where a spread binds the rest of elements or fields.
+// Names of variables in scope and other expressions are compared with the value.
+type MatchCase struct {
+	Pos      lexer.Pos
+	Patterns []Expr
+	Body     []Stmt
+}
+
+func (c *MatchCase) Inspect() string {
+	var patterns []string
+	for _, p := range c.Patterns {
+		patterns = append(patterns, p.Inspect())
+	}
+	var body []string
+	for _, s := range c.Body {
+		body = append(body, s.Inspect())
+	}
+	return fmt.Sprintf("MatchCase{[%s], %s}", strings.Join(patterns, ", "), strings.Join(body, ", "))
+}
+
+// TryStmt runs Body, then Catch with the error bound to Name if Body throws, and Finally in any case.
+// Name is empty if there is no `catch` clause.
+type TryStmt struct {
+	Pos     lexer.Pos
+	Body    []Stmt
+	Name    string
+	Catch   []Stmt
+	Finally []Stmt
+}
+
+func (stmt *TryStmt) Inspect() string {
+	inspect := func(stmts []Stmt) string {
+		var body []string
+		for _, s := range stmts {
+			body = append(body, s.Inspect())
+		}
+		return strings.Join(body, ", ")
+	}
+	return fmt.Sprintf("TryStmt{[%s], \"%s\", [%s], [%s]}", inspect(stmt.Body), stmt.Name, inspect(stmt.Catch), inspect(stmt.Finally))
+}
+
+func (stmt *TryStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
+
+// ThrowStmt raises Value, which is either an error or a string message, e.g. `throw 'not found'`.
+type ThrowStmt struct {
+	Pos   lexer.Pos
+	Value Expr
+}
+
+func (stmt *ThrowStmt) Inspect() string {
+	return fmt.Sprintf("ThrowStmt{%s}", stmt.Value.Inspect())
+}
+
+func (stmt *ThrowStmt) Span() lexer.Pos {
+	return stmt.Pos
+}
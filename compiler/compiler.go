@@ -0,0 +1,864 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/fj68/vvlang/ast"
+	"github.com/fj68/vvlang/lexer"
+)
+
+// Compile lowers program into the top-level Proto.
+//
+// Variables assigned at the top-level are globals. Inside functions,
+// assigning to a name makes it a local of the function unless the name is
+// a local of an enclosing function or a global. isGlobal reports names which
+// are already defined as globals when compiling, e.g. builtins.
+func Compile(program []ast.Stmt, isGlobal func(name string) bool) (*Proto, error) {
+	c := &compiler{
+		isGlobal: isGlobal,
+		globals:  map[string]bool{},
+		defined:  map[string]bool{},
+	}
+	for _, name := range assignedNames(program) {
+		c.globals[name] = true
+	}
+	fs := c.newFuncState(nil, "", nil)
+	if err := c.compileBody(fs, program); err != nil {
+		return nil, err
+	}
+	c.emit(fs, OpNil, 0, 0, lexer.Pos{})
+	c.emit(fs, OpReturn, 0, 0, lexer.Pos{})
+	return fs.proto, nil
+}
+
+type compiler struct {
+	isGlobal func(name string) bool
+	globals  map[string]bool
+	// defined holds globals assigned so far, see inScope.
+	defined map[string]bool
+}
+
+type funcState struct {
+	proto  *Proto
+	parent *funcState
+	locals map[string]int
+	// defined holds locals assigned so far, see inScope.
+	defined map[string]bool
+	consts  map[any]int
+	names   map[string]int
+	loops   []*loopState
+	tries   []*tryState
+	depth   int
+	// skips holds jumps of `?.` to the end of the chain being compiled.
+	skips []int
+}
+
+type loopState struct {
+	start  int
+	breaks []int
+	// tries is the number of try statements the loop is in.
+	tries int
+}
+
+// tryState is a try statement whose handler is active, which break, continue and return leave.
+type tryState struct {
+	finally []ast.Stmt
+	// loops is the number of loops the statement is in.
+	loops int
+}
+
+func (c *compiler) newFuncState(parent *funcState, name string, params []string) *funcState {
+	fs := &funcState{
+		proto:   &Proto{Name: name, Params: len(params)},
+		parent:  parent,
+		locals:  map[string]int{},
+		defined: map[string]bool{},
+		consts:  map[any]int{},
+		names:   map[string]int{},
+	}
+	for _, param := range params {
+		fs.declare(param)
+		fs.defined[param] = true
+	}
+	return fs
+}
+
+func (fs *funcState) isMain() bool {
+	return fs.parent == nil
+}
+
+func (fs *funcState) declare(name string) int {
+	if slot, ok := fs.locals[name]; ok {
+		return slot
+	}
+	slot := len(fs.proto.Locals)
+	fs.locals[name] = slot
+	fs.proto.Locals = append(fs.proto.Locals, name)
+	return slot
+}
+
+func (fs *funcState) constant(v any) int {
+	if i, ok := fs.consts[v]; ok {
+		return i
+	}
+	i := len(fs.proto.Consts)
+	fs.consts[v] = i
+	fs.proto.Consts = append(fs.proto.Consts, v)
+	return i
+}
+
+func (fs *funcState) name(name string) int {
+	if i, ok := fs.names[name]; ok {
+		return i
+	}
+	i := len(fs.proto.Names)
+	fs.names[name] = i
+	fs.proto.Names = append(fs.proto.Names, name)
+	return i
+}
+
+func (c *compiler) emit(fs *funcState, op Op, a int, b int, pos lexer.Pos) int {
+	in := Instr{op, a, b}
+	fs.proto.Code = append(fs.proto.Code, in)
+	fs.proto.Pos = append(fs.proto.Pos, pos)
+	fs.depth += stackEffect(in)
+	if fs.proto.MaxStack < fs.depth {
+		fs.proto.MaxStack = fs.depth
+	}
+	return len(fs.proto.Code) - 1
+}
+
+// pushed counts n values pushed by the last instruction in addition to its stackEffect.
+func (c *compiler) pushed(fs *funcState, n int) {
+	fs.depth += n
+	if fs.proto.MaxStack < fs.depth {
+		fs.proto.MaxStack = fs.depth
+	}
+}
+
+// patch makes the jump at pc go to the next instruction to be emitted.
+func (c *compiler) patch(fs *funcState, pc int) {
+	fs.proto.Code[pc].A = len(fs.proto.Code)
+}
+
+func (c *compiler) errorf(pos lexer.Pos, format string, args ...any) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c *compiler) isKnownGlobal(name string) bool {
+	return c.globals[name] || (c.isGlobal != nil && c.isGlobal(name))
+}
+
+type varKind int
+
+const (
+	varLocal varKind = iota
+	varOuter
+	varGlobal
+)
+
+// resolve finds the variable a name refers to from fs.
+func (c *compiler) resolve(fs *funcState, name string) (kind varKind, depth int, slot int) {
+	for f := fs; f != nil; f = f.parent {
+		if slot, ok := f.locals[name]; ok {
+			if depth == 0 {
+				return varLocal, 0, slot
+			}
+			return varOuter, depth, slot
+		}
+		depth++
+	}
+	return varGlobal, 0, 0
+}
+
+// declareLocals declares names assigned in body as locals of fs,
+// unless they refer to variables of enclosing functions or globals.
+func (c *compiler) declareLocals(fs *funcState, body []ast.Stmt) {
+	for _, name := range assignedNames(body) {
+		if kind, _, _ := c.resolve(fs, name); kind == varGlobal && !c.isKnownGlobal(name) {
+			fs.declare(name)
+		}
+	}
+}
+
+// assignedNames returns names assigned by statements in body,
+// not including ones in nested functions.
+func assignedNames(body []ast.Stmt) []string {
+	var names []string
+	for _, stmt := range body {
+		switch v := stmt.(type) {
+		case *ast.VarDeclStmt:
+			names = append(names, v.Name)
+		case *ast.ExprStmt:
+			if f, ok := v.Expr.(*ast.FunLiteralExpr); ok && f.Name != "" {
+				names = append(names, f.Name)
+			}
+		case *ast.IfStmt:
+			names = append(names, assignedNames(v.Then)...)
+			names = append(names, assignedNames(v.Else)...)
+		case *ast.WhileStmt:
+			names = append(names, assignedNames(v.Body)...)
+		case *ast.MatchStmt:
+			for _, mc := range v.Cases {
+				for _, p := range mc.Patterns {
+					names = append(names, patternNames(p)...)
+				}
+				names = append(names, assignedNames(mc.Body)...)
+			}
+			names = append(names, assignedNames(v.Else)...)
+		case *ast.ForStmt:
+			if v.Key != "" {
+				names = append(names, v.Key)
+			}
+			names = append(names, v.Value)
+			names = append(names, assignedNames(v.Body)...)
+		case *ast.TryStmt:
+			names = append(names, assignedNames(v.Body)...)
+			if v.Name != "" {
+				names = append(names, v.Name)
+			}
+			names = append(names, assignedNames(v.Catch)...)
+			names = append(names, assignedNames(v.Finally)...)
+		}
+	}
+	return names
+}
+
+func (c *compiler) load(fs *funcState, name string, pos lexer.Pos) {
+	switch kind, depth, slot := c.resolve(fs, name); kind {
+	case varLocal:
+		c.emit(fs, OpLoadLocal, slot, 0, pos)
+	case varOuter:
+		c.emit(fs, OpLoadOuter, depth, slot, pos)
+	default:
+		c.emit(fs, OpLoadGlobal, fs.name(name), 0, pos)
+	}
+}
+
+func (c *compiler) store(fs *funcState, name string, pos lexer.Pos) {
+	kind, depth, slot := c.resolve(fs, name)
+	if kind == varGlobal && !fs.isMain() && !c.isKnownGlobal(name) {
+		kind, slot = varLocal, fs.declare(name)
+	}
+	switch kind {
+	case varLocal:
+		c.emit(fs, OpStoreLocal, slot, 0, pos)
+		fs.defined[name] = true
+	case varOuter:
+		c.emit(fs, OpStoreOuter, depth, slot, pos)
+		f := fs
+		for i := 0; i < depth; i++ {
+			f = f.parent
+		}
+		f.defined[name] = true
+	default:
+		c.emit(fs, OpStoreGlobal, fs.name(name), 0, pos)
+		c.defined[name] = true
+	}
+}
+
+// inScope reports whether name is a variable assigned before the code being compiled,
+// or a global defined by the host.
+// Locals are declared for the whole function beforehand, so this tells them apart
+// from names which are assigned first by the code.
+func (c *compiler) inScope(fs *funcState, name string) bool {
+	switch kind, depth, _ := c.resolve(fs, name); kind {
+	case varLocal, varOuter:
+		f := fs
+		for i := 0; i < depth; i++ {
+			f = f.parent
+		}
+		return f.defined[name]
+	default:
+		return c.defined[name] || (c.isGlobal != nil && c.isGlobal(name))
+	}
+}
+
+func (c *compiler) compileBody(fs *funcState, body []ast.Stmt) error {
+	for _, stmt := range body {
+		if err := c.compileStmt(fs, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileStmt(fs *funcState, stmt ast.Stmt) error {
+	switch v := stmt.(type) {
+	case *ast.ExprStmt:
+		if err := c.compileExpr(fs, v.Expr); err != nil {
+			return err
+		}
+		c.emit(fs, OpPop, 0, 0, v.Span())
+		return nil
+	case *ast.ReturnStmt:
+		return c.compileReturnStmt(fs, v)
+	case *ast.VarDeclStmt:
+		if err := c.compileExpr(fs, v.Body); err != nil {
+			return err
+		}
+		c.store(fs, v.Name, v.Span())
+		return nil
+	case *ast.AssignStmt:
+		return c.compileAssignStmt(fs, v)
+	case *ast.IfStmt:
+		return c.compileIfStmt(fs, v)
+	case *ast.WhileStmt:
+		return c.compileWhileStmt(fs, v)
+	case *ast.ForStmt:
+		return c.compileForStmt(fs, v)
+	case *ast.MatchStmt:
+		return c.compileMatchStmt(fs, v)
+	case *ast.TryStmt:
+		return c.compileTryStmt(fs, v)
+	case *ast.ThrowStmt:
+		if err := c.compileExpr(fs, v.Value); err != nil {
+			return err
+		}
+		c.emit(fs, OpThrow, 0, 0, v.Span())
+		return nil
+	case *ast.BreakStmt:
+		if len(fs.loops) == 0 {
+			return c.errorf(v.Span(), "break outside of loop")
+		}
+		loop := fs.loops[len(fs.loops)-1]
+		if err := c.exitTries(fs, loop.tries, v.Span()); err != nil {
+			return err
+		}
+		loop.breaks = append(loop.breaks, c.emit(fs, OpJump, 0, 0, v.Span()))
+		return nil
+	case *ast.ContinueStmt:
+		if len(fs.loops) == 0 {
+			return c.errorf(v.Span(), "continue outside of loop")
+		}
+		loop := fs.loops[len(fs.loops)-1]
+		if err := c.exitTries(fs, loop.tries, v.Span()); err != nil {
+			return err
+		}
+		c.emit(fs, OpJump, loop.start, 0, v.Span())
+		return nil
+	default:
+		return c.errorf(stmt.Span(), "unknown stmt: %s", stmt.Inspect())
+	}
+}
+
+func (c *compiler) compileReturnStmt(fs *funcState, stmt *ast.ReturnStmt) error {
+	if stmt.Value == nil {
+		c.emit(fs, OpNil, 0, 0, stmt.Span())
+	} else if err := c.compileExpr(fs, stmt.Value); err != nil {
+		return err
+	}
+	// the value stays on the stack while finally clauses run
+	if err := c.exitTries(fs, 0, stmt.Span()); err != nil {
+		return err
+	}
+	c.emit(fs, OpReturn, 0, 0, stmt.Span())
+	return nil
+}
+
+func (c *compiler) compileAssignStmt(fs *funcState, stmt *ast.AssignStmt) error {
+	switch target := stmt.Target.(type) {
+	case *ast.FieldAccessExpr:
+		if err := c.compileExpr(fs, target.Record); err != nil {
+			return err
+		}
+		if err := c.compileExpr(fs, stmt.Value); err != nil {
+			return err
+		}
+		c.emit(fs, OpSetField, fs.name(target.Field), 0, stmt.Span())
+		return nil
+	case *ast.IndexExpr:
+		if err := c.compileExpr(fs, target.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpr(fs, target.Index); err != nil {
+			return err
+		}
+		if err := c.compileExpr(fs, stmt.Value); err != nil {
+			return err
+		}
+		c.emit(fs, OpSetIndex, 0, 0, stmt.Span())
+		return nil
+	default:
+		return c.errorf(target.Span(), "cannot assign to %s", target.Inspect())
+	}
+}
+
+func (c *compiler) compileIfStmt(fs *funcState, stmt *ast.IfStmt) error {
+	if err := c.compileExpr(fs, stmt.Cond); err != nil {
+		return err
+	}
+	jumpToElse := c.emit(fs, OpJumpIfFalse, 0, 0, stmt.Cond.Span())
+	if err := c.compileBody(fs, stmt.Then); err != nil {
+		return err
+	}
+	if stmt.Else == nil {
+		c.patch(fs, jumpToElse)
+		return nil
+	}
+	jumpToEnd := c.emit(fs, OpJump, 0, 0, stmt.Span())
+	c.patch(fs, jumpToElse)
+	if err := c.compileBody(fs, stmt.Else); err != nil {
+		return err
+	}
+	c.patch(fs, jumpToEnd)
+	return nil
+}
+
+func (c *compiler) compileWhileStmt(fs *funcState, stmt *ast.WhileStmt) error {
+	loop := &loopState{start: len(fs.proto.Code), tries: len(fs.tries)}
+	if err := c.compileExpr(fs, stmt.Cond); err != nil {
+		return err
+	}
+	loop.breaks = append(loop.breaks, c.emit(fs, OpJumpIfFalse, 0, 0, stmt.Cond.Span()))
+
+	fs.loops = append(fs.loops, loop)
+	err := c.compileBody(fs, stmt.Body)
+	fs.loops = fs.loops[:len(fs.loops)-1]
+	if err != nil {
+		return err
+	}
+
+	c.emit(fs, OpJump, loop.start, 0, stmt.Span())
+	for _, pc := range loop.breaks {
+		c.patch(fs, pc)
+	}
+	return nil
+}
+
+// compileForStmt keeps the iterator on the stack during the loop,
+// so both the end of iteration and break jump to the Pop discarding it.
+func (c *compiler) compileForStmt(fs *funcState, stmt *ast.ForStmt) error {
+	if err := c.compileExpr(fs, stmt.Iter); err != nil {
+		return err
+	}
+	c.emit(fs, OpIter, 0, 0, stmt.Iter.Span())
+
+	loop := &loopState{start: len(fs.proto.Code), tries: len(fs.tries)}
+	if stmt.Key == "" {
+		loop.breaks = append(loop.breaks, c.emit(fs, OpNext, 0, 0, stmt.Span()))
+	} else {
+		loop.breaks = append(loop.breaks, c.emit(fs, OpNext, 0, 1, stmt.Span()))
+	}
+	c.store(fs, stmt.Value, stmt.Span())
+	if stmt.Key != "" {
+		c.store(fs, stmt.Key, stmt.Span())
+	}
+
+	fs.loops = append(fs.loops, loop)
+	err := c.compileBody(fs, stmt.Body)
+	fs.loops = fs.loops[:len(fs.loops)-1]
+	if err != nil {
+		return err
+	}
+
+	c.emit(fs, OpJump, loop.start, 0, stmt.Span())
+	for _, pc := range loop.breaks {
+		c.patch(fs, pc)
+	}
+	c.emit(fs, OpPop, 0, 0, stmt.Span())
+	return nil
+}
+
+// compileMatchStmt tries cases in order, keeping the subject on the stack until one of them matches.
+func (c *compiler) compileMatchStmt(fs *funcState, stmt *ast.MatchStmt) error {
+	if err := c.compileExpr(fs, stmt.Subject); err != nil {
+		return err
+	}
+	// patterns are compiled before bodies, so that names assigned by an earlier case are new to later ones
+	patterns := make([]*Pattern, len(stmt.Cases))
+	exprs := make([][]ast.Expr, len(stmt.Cases))
+	for i, mc := range stmt.Cases {
+		pattern, es, err := c.compilePatterns(fs, mc.Patterns)
+		if err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		for _, name := range pattern.Bindings() {
+			if seen[name] {
+				return c.errorf(mc.Pos, "name '%s' is bound twice in a pattern", name)
+			}
+			seen[name] = true
+		}
+		patterns[i], exprs[i] = pattern, es
+	}
+
+	depth := fs.depth
+	var ends []int
+	for i, mc := range stmt.Cases {
+		if err := c.compileExprs(fs, exprs[i]); err != nil {
+			return err
+		}
+		names := patterns[i].Bindings()
+		fs.proto.Patterns = append(fs.proto.Patterns, patterns[i])
+		next := c.emit(fs, OpMatch, len(fs.proto.Patterns)-1, 0, mc.Pos)
+		// and values of the expressions are popped
+		fs.depth -= len(exprs[i])
+		c.pushed(fs, len(names))
+		for i := len(names) - 1; 0 <= i; i-- {
+			c.store(fs, names[i], mc.Pos)
+		}
+		if err := c.compileBody(fs, mc.Body); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(fs, OpJump, 0, 0, mc.Pos))
+		fs.proto.Code[next].B = len(fs.proto.Code)
+		fs.depth = depth
+	}
+
+	// no case matched
+	c.emit(fs, OpPop, 0, 0, stmt.Span())
+	if err := c.compileBody(fs, stmt.Else); err != nil {
+		return err
+	}
+	for _, pc := range ends {
+		c.patch(fs, pc)
+	}
+	return nil
+}
+
+func (c *compiler) compileExprs(fs *funcState, exprs []ast.Expr) error {
+	for _, expr := range exprs {
+		if err := c.compileExpr(fs, expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileExpr(fs *funcState, expr ast.Expr) error {
+	switch v := expr.(type) {
+	case *ast.NilLiteralExpr:
+		c.emit(fs, OpNil, 0, 0, v.Span())
+	case *ast.BoolLiteralExpr:
+		c.emit(fs, OpConst, fs.constant(v.Value), 0, v.Span())
+	case *ast.IntLiteralExpr:
+		c.emit(fs, OpConst, fs.constant(v.Value), 0, v.Span())
+	case *ast.NumberLiteralExpr:
+		c.emit(fs, OpConst, fs.constant(v.Value), 0, v.Span())
+	case *ast.StringLiteralExpr:
+		c.emit(fs, OpConst, fs.constant(v.Value), 0, v.Span())
+	case *ast.InterpolatedStringLiteralExpr:
+		return c.compileInterpolatedStringLiteralExpr(fs, v)
+	case *ast.RecordLiteralExpr:
+		return c.compileRecordLiteralExpr(fs, v)
+	case *ast.FieldAccessExpr, *ast.FunCallExpr, *ast.IndexExpr, *ast.SliceExpr:
+		return c.compileChain(fs, v)
+	case *ast.FunLiteralExpr:
+		return c.compileFunLiteralExpr(fs, v)
+	case *ast.VarRefExpr:
+		c.load(fs, v.Name, v.Span())
+	case *ast.InfixExpr:
+		return c.compileInfixExpr(fs, v)
+	case *ast.LogicalExpr:
+		return c.compileLogicalExpr(fs, v)
+	case *ast.ListLiteralExpr:
+		return c.compileListLiteralExpr(fs, v)
+	case *ast.SpreadExpr:
+		return c.errorf(v.Span(), "spread expression can only be used inside list or record literals")
+	case *ast.PrefixExpr:
+		op, ok := prefixOps[v.Op]
+		if !ok {
+			return c.errorf(v.Span(), "unknown prefix operator: %s", v.Op)
+		}
+		if err := c.compileExpr(fs, v.Right); err != nil {
+			return err
+		}
+		c.emit(fs, op, 0, 0, v.Span())
+	default:
+		return c.errorf(expr.Span(), "unknown expr: %s", expr.Inspect())
+	}
+	return nil
+}
+
+// compileChain compiles a chain of field accesses, indexes, slices and calls,
+// where `?.` skips the rest of the chain if the record is nil, e.g. `a?.b.c` is nil if a is nil.
+func (c *compiler) compileChain(fs *funcState, expr ast.Expr) error {
+	skips := fs.skips
+	fs.skips = nil
+	err := c.compileChainLink(fs, expr)
+	for _, pc := range fs.skips {
+		c.patch(fs, pc)
+	}
+	fs.skips = skips
+	return err
+}
+
+// compileChainLink compiles expr as a part of the chain being compiled.
+func (c *compiler) compileChainLink(fs *funcState, expr ast.Expr) error {
+	switch v := expr.(type) {
+	case *ast.FieldAccessExpr:
+		if err := c.compileChainLink(fs, v.Record); err != nil {
+			return err
+		}
+		if v.Optional {
+			fs.skips = append(fs.skips, c.emit(fs, OpJumpIfNil, 0, 0, v.Span()))
+			c.emit(fs, OpField, fs.name(v.Field), 1, v.Span())
+		} else {
+			c.emit(fs, OpField, fs.name(v.Field), 0, v.Span())
+		}
+	case *ast.FunCallExpr:
+		if err := c.compileChainLink(fs, v.Fun); err != nil {
+			return err
+		}
+		if err := c.compileExprs(fs, v.Args); err != nil {
+			return err
+		}
+		c.emit(fs, OpCall, len(v.Args), 0, v.Span())
+	case *ast.IndexExpr:
+		if err := c.compileChainLink(fs, v.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpr(fs, v.Index); err != nil {
+			return err
+		}
+		c.emit(fs, OpIndex, 0, 0, v.Span())
+	case *ast.SliceExpr:
+		return c.compileSliceExpr(fs, v)
+	default:
+		return c.compileExpr(fs, expr)
+	}
+	return nil
+}
+
+func (c *compiler) compileInterpolatedStringLiteralExpr(fs *funcState, expr *ast.InterpolatedStringLiteralExpr) error {
+	c.emit(fs, OpConst, fs.constant(expr.Texts[0]), 0, expr.Span())
+	for i, value := range expr.Values {
+		if err := c.compileExpr(fs, value); err != nil {
+			return err
+		}
+		c.emit(fs, OpConst, fs.constant(expr.Texts[i+1]), 0, expr.Span())
+	}
+	c.emit(fs, OpConcat, len(expr.Values)*2+1, 0, expr.Span())
+	return nil
+}
+
+func (c *compiler) compileRecordLiteralExpr(fs *funcState, expr *ast.RecordLiteralExpr) error {
+	c.emit(fs, OpRecord, 0, 0, expr.Span())
+	for _, elem := range expr.Elements {
+		switch e := elem.(type) {
+		case *ast.RecordField:
+			if err := c.compileExpr(fs, e.Value); err != nil {
+				return err
+			}
+			c.emit(fs, OpSetKey, fs.name(e.Key), 0, e.Value.Span())
+		case *ast.RecordSpread:
+			if err := c.compileExpr(fs, e.Expr); err != nil {
+				return err
+			}
+			c.emit(fs, OpMerge, 0, 0, e.Expr.Span())
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileFunLiteralExpr(fs *funcState, expr *ast.FunLiteralExpr) error {
+	inner := c.newFuncState(fs, expr.Name, expr.Args)
+	c.declareLocals(inner, expr.Body)
+	if err := c.compileBody(inner, expr.Body); err != nil {
+		return err
+	}
+	c.emit(inner, OpNil, 0, 0, expr.Span())
+	c.emit(inner, OpReturn, 0, 0, expr.Span())
+
+	fs.proto.Protos = append(fs.proto.Protos, inner.proto)
+	c.emit(fs, OpClosure, len(fs.proto.Protos)-1, 0, expr.Span())
+	if expr.Name != "" {
+		c.emit(fs, OpDup, 0, 0, expr.Span())
+		c.store(fs, expr.Name, expr.Span())
+	}
+	return nil
+}
+
+var prefixOps = map[string]Op{
+	"-":   OpNeg,
+	"not": OpNot,
+}
+
+var binaryOps = map[string]Op{
+	"+":   OpAdd,
+	"-":   OpSub,
+	"*":   OpMul,
+	"/":   OpDiv,
+	"mod": OpMod,
+	"div": OpIntDiv,
+	"==":  OpEqual,
+	"<":   OpLess,
+	"<=":  OpLessEq,
+	"!=":  OpNotEqual,
+	">":   OpGreater,
+	">=":  OpGreaterEq,
+}
+
+func (c *compiler) compileInfixExpr(fs *funcState, expr *ast.InfixExpr) error {
+	op, ok := binaryOps[expr.Op]
+	if !ok {
+		return c.errorf(expr.Span(), "unknown operator: %s", expr.Op)
+	}
+	if err := c.compileExpr(fs, expr.Left); err != nil {
+		return err
+	}
+	if err := c.compileExpr(fs, expr.Right); err != nil {
+		return err
+	}
+	c.emit(fs, op, 0, 0, expr.Span())
+	return nil
+}
+
+func (c *compiler) compileLogicalExpr(fs *funcState, expr *ast.LogicalExpr) error {
+	var op Op
+	var kind int
+	switch expr.Op {
+	case "and":
+		op, kind = OpJumpIfFalseOrPop, 0
+	case "or":
+		op, kind = OpJumpIfTrueOrPop, 1
+	default:
+		return c.errorf(expr.Span(), "unknown operator: %s", expr.Op)
+	}
+	if err := c.compileExpr(fs, expr.Left); err != nil {
+		return err
+	}
+	jump := c.emit(fs, op, 0, kind, expr.Left.Span())
+	if err := c.compileExpr(fs, expr.Right); err != nil {
+		return err
+	}
+	c.emit(fs, OpExpectBool, kind, 0, expr.Right.Span())
+	c.patch(fs, jump)
+	return nil
+}
+
+func (c *compiler) compileListLiteralExpr(fs *funcState, expr *ast.ListLiteralExpr) error {
+	hasSpread := false
+	for _, elem := range expr.Elements {
+		if _, ok := elem.(*ast.SpreadExpr); ok {
+			hasSpread = true
+		}
+	}
+	if !hasSpread {
+		if err := c.compileExprs(fs, expr.Elements); err != nil {
+			return err
+		}
+		c.emit(fs, OpList, len(expr.Elements), 0, expr.Span())
+		return nil
+	}
+
+	c.emit(fs, OpList, 0, 0, expr.Span())
+	for _, elem := range expr.Elements {
+		if spread, ok := elem.(*ast.SpreadExpr); ok {
+			if err := c.compileExpr(fs, spread.Expr); err != nil {
+				return err
+			}
+			c.emit(fs, OpExtend, 0, 0, spread.Span())
+			continue
+		}
+		if err := c.compileExpr(fs, elem); err != nil {
+			return err
+		}
+		c.emit(fs, OpAppend, 0, 0, elem.Span())
+	}
+	return nil
+}
+
+func (c *compiler) compileSliceExpr(fs *funcState, expr *ast.SliceExpr) error {
+	if err := c.compileChainLink(fs, expr.Left); err != nil {
+		return err
+	}
+	flags := 0
+	if expr.Start != nil {
+		if err := c.compileExpr(fs, expr.Start); err != nil {
+			return err
+		}
+		flags |= SliceStart
+	}
+	if expr.End != nil {
+		if err := c.compileExpr(fs, expr.End); err != nil {
+			return err
+		}
+		flags |= SliceEnd
+	}
+	c.emit(fs, OpSlice, flags, 0, expr.Span())
+	return nil
+}
+
+// compileTryStmt compiles Finally at every exit of the statement: the ends of Body and Catch,
+// break, continue and return, and errors not caught, which are raised again after Finally.
+func (c *compiler) compileTryStmt(fs *funcState, stmt *ast.TryStmt) error {
+	depth := fs.depth
+	hasCatch := stmt.Name != ""
+	rethrow := !hasCatch || len(stmt.Finally) != 0
+	var ends []int
+
+	try := c.emit(fs, OpTry, 0, 0, stmt.Span())
+	if err := c.compileTryBody(fs, stmt.Body, stmt.Finally); err != nil {
+		return err
+	}
+	c.emit(fs, OpEndTry, 0, 0, stmt.Span())
+	if err := c.compileBody(fs, stmt.Finally); err != nil {
+		return err
+	}
+	ends = append(ends, c.emit(fs, OpJump, 0, 0, stmt.Span()))
+
+	if hasCatch {
+		// the error is pushed when caught
+		c.patch(fs, try)
+		fs.depth = depth
+		c.pushed(fs, 1)
+		c.store(fs, stmt.Name, stmt.Span())
+		if rethrow {
+			try = c.emit(fs, OpTry, 0, 0, stmt.Span())
+			if err := c.compileTryBody(fs, stmt.Catch, stmt.Finally); err != nil {
+				return err
+			}
+			c.emit(fs, OpEndTry, 0, 0, stmt.Span())
+		} else if err := c.compileBody(fs, stmt.Catch); err != nil {
+			return err
+		}
+		if err := c.compileBody(fs, stmt.Finally); err != nil {
+			return err
+		}
+		ends = append(ends, c.emit(fs, OpJump, 0, 0, stmt.Span()))
+	}
+
+	if rethrow {
+		c.patch(fs, try)
+		fs.depth = depth
+		c.pushed(fs, 1)
+		if err := c.compileBody(fs, stmt.Finally); err != nil {
+			return err
+		}
+		c.emit(fs, OpThrow, 0, 0, stmt.Span())
+	}
+
+	for _, pc := range ends {
+		c.patch(fs, pc)
+	}
+	fs.depth = depth
+	return nil
+}
+
+// compileTryBody compiles body while the handler of a try statement is active.
+func (c *compiler) compileTryBody(fs *funcState, body []ast.Stmt, finally []ast.Stmt) error {
+	fs.tries = append(fs.tries, &tryState{finally: finally, loops: len(fs.loops)})
+	err := c.compileBody(fs, body)
+	fs.tries = fs.tries[:len(fs.tries)-1]
+	return err
+}
+
+// exitTries leaves try statements but the outermost n before jumping out of them,
+// stopping their handlers and running their finally clauses from the innermost.
+func (c *compiler) exitTries(fs *funcState, n int, pos lexer.Pos) error {
+	tries, loops := fs.tries, fs.loops
+	defer func() {
+		fs.tries, fs.loops = tries, loops
+	}()
+	for i := len(tries) - 1; n <= i; i-- {
+		c.emit(fs, OpEndTry, 0, 0, pos)
+		// finally clauses are outside of the statement and loops in it
+		fs.tries, fs.loops = tries[:i], loops[:tries[i].loops]
+		if err := c.compileBody(fs, tries[i].finally); err != nil {
+			return err
+		}
+	}
+	return nil
+}
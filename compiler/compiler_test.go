@@ -0,0 +1,321 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/parser"
+)
+
+func compile(t *testing.T, text string) *Proto {
+	t.Helper()
+	program, err := parser.Parse([]rune(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proto, err := Compile(program, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proto
+}
+
+func TestCompileFun(t *testing.T) {
+	proto := compile(t, `x = 1
+fun f(a)
+  y = a + x
+  return y
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 Const 0 (1)
+   1 StoreGlobal 0 (x)
+   2 Closure 0
+   3 Dup
+   4 StoreGlobal 1 (f)
+   5 Pop
+   6 Nil
+   7 Return
+fun f params=1 locals=[a, y]
+   0 LoadLocal 0
+   1 LoadGlobal 0 (x)
+   2 Add
+   3 StoreLocal 1
+   4 LoadLocal 1
+   5 Return
+   6 Nil
+   7 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestCompileWhile(t *testing.T) {
+	proto := compile(t, `i = 0
+while i < 3
+  if i == 2
+    break
+  end
+  i = i + 1
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 Const 0 (0)
+   1 StoreGlobal 0 (i)
+   2 LoadGlobal 0 (i)
+   3 Const 1 (3)
+   4 Less
+   5 JumpIfFalse 16
+   6 LoadGlobal 0 (i)
+   7 Const 2 (2)
+   8 Equal
+   9 JumpIfFalse 11
+  10 Jump 16
+  11 LoadGlobal 0 (i)
+  12 Const 3 (1)
+  13 Add
+  14 StoreGlobal 0 (i)
+  15 Jump 2
+  16 Nil
+  17 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestCompileFor(t *testing.T) {
+	proto := compile(t, `for i, x in xs
+  if x
+    break
+  end
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 LoadGlobal 0 (xs)
+   1 Iter
+   2 Next 9 1
+   3 StoreGlobal 1 (x)
+   4 StoreGlobal 2 (i)
+   5 LoadGlobal 1 (x)
+   6 JumpIfFalse 8
+   7 Jump 9
+   8 Jump 2
+   9 Pop
+  10 Nil
+  11 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestCompileForLocals(t *testing.T) {
+	proto := compile(t, `fun f(xs)
+  for x in xs
+    y = x
+  end
+end`)
+	f := proto.Protos[0]
+	if actual := strings.Join(f.Locals, " "); actual != "xs x y" {
+		t.Fatalf("expected locals xs x y, but got %s", actual)
+	}
+}
+
+func TestCompileMatch(t *testing.T) {
+	proto := compile(t, `match x
+case 1, 'a'
+  y = 1
+case [a, ...b]
+  y = a
+else
+  y = 0
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 LoadGlobal 0 (x)
+   1 Match 0 5 (const 0 | const 1)
+   2 Const 0 (1)
+   3 StoreGlobal 1 (y)
+   4 Jump 14
+   5 Match 1 11 ([a, ...b])
+   6 StoreGlobal 2 (b)
+   7 StoreGlobal 3 (a)
+   8 LoadGlobal 3 (a)
+   9 StoreGlobal 1 (y)
+  10 Jump 14
+  11 Pop
+  12 Const 2 (0)
+  13 StoreGlobal 1 (y)
+  14 Nil
+  15 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+	if proto.MaxStack != 2 {
+		t.Fatalf("expected max stack 2, but got %d", proto.MaxStack)
+	}
+}
+
+func TestCompileTry(t *testing.T) {
+	proto := compile(t, `try
+  f()
+catch e
+  g(e)
+finally
+  h()
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 Try 9
+   1 LoadGlobal 0 (f)
+   2 Call 0
+   3 Pop
+   4 EndTry
+   5 LoadGlobal 1 (h)
+   6 Call 0
+   7 Pop
+   8 Jump 24
+   9 StoreGlobal 2 (e)
+  10 Try 20
+  11 LoadGlobal 3 (g)
+  12 LoadGlobal 2 (e)
+  13 Call 1
+  14 Pop
+  15 EndTry
+  16 LoadGlobal 1 (h)
+  17 Call 0
+  18 Pop
+  19 Jump 24
+  20 LoadGlobal 1 (h)
+  21 Call 0
+  22 Pop
+  23 Throw
+  24 Nil
+  25 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestCompileBreakThroughFinally(t *testing.T) {
+	proto := compile(t, `while true
+  try
+    break
+  finally
+    h()
+  end
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 Const 0 (true)
+   1 JumpIfFalse 18
+   2 Try 13
+   3 EndTry
+   4 LoadGlobal 0 (h)
+   5 Call 0
+   6 Pop
+   7 Jump 18
+   8 EndTry
+   9 LoadGlobal 0 (h)
+  10 Call 0
+  11 Pop
+  12 Jump 17
+  13 LoadGlobal 0 (h)
+  14 Call 0
+  15 Pop
+  16 Throw
+  17 Jump 0
+  18 Nil
+  19 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestCompileOuterVariable(t *testing.T) {
+	proto := compile(t, `fun counter()
+  count = 0
+  return fun()
+    count = count + 1
+    return count
+  end
+end`)
+	inner := proto.Protos[0].Protos[0]
+	if inner.Code[0] != (Instr{OpLoadOuter, 1, 0}) {
+		t.Fatalf("expected LoadOuter 1 0, but got %s", inner.Code[0])
+	}
+	if len(inner.Locals) != 0 {
+		t.Fatalf("expected no locals, but got %v", inner.Locals)
+	}
+}
+
+func TestCompileMatchExpr(t *testing.T) {
+	// k is in scope and compared, while j is new and bound
+	proto := compile(t, `k = 1
+match x
+case k, p.q
+  y = 1
+case [j, k]
+  y = j
+end`)
+	expected := `fun <anonymous> params=0 locals=[]
+   0 Const 0 (1)
+   1 StoreGlobal 0 (k)
+   2 LoadGlobal 1 (x)
+   3 LoadGlobal 0 (k)
+   4 LoadGlobal 2 (p)
+   5 Field 3 (q)
+   6 Match 0 10 (expr 0 | expr 1)
+   7 Const 0 (1)
+   8 StoreGlobal 4 (y)
+   9 Jump 17
+  10 LoadGlobal 0 (k)
+  11 Match 1 16 ([j, expr 0])
+  12 StoreGlobal 5 (j)
+  13 LoadGlobal 5 (j)
+  14 StoreGlobal 4 (y)
+  15 Jump 17
+  16 Pop
+  17 Nil
+  18 Return
+`
+	if actual := proto.Disassemble(); actual != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+	if proto.MaxStack != 3 {
+		t.Fatalf("expected 3, but got %d", proto.MaxStack)
+	}
+}
+
+func TestCompileMaxStack(t *testing.T) {
+	proto := compile(t, `x = [1, 2, [3, 4]]`)
+	if proto.MaxStack != 4 {
+		t.Fatalf("expected 4, but got %d", proto.MaxStack)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"break", "break outside of loop"},
+		{"fun f() continue end", "continue outside of loop"},
+		{"match x case [a, b] , 1 end", "pattern in a case of multiple patterns must not bind names"},
+		{"match x case [...a, b] end", "rest of list pattern must be the last"},
+		{"match x case [a, ...[b]] end", "rest of pattern must be a name"},
+		{"match x case [a, { k = a }] end", "name 'a' is bound twice in a pattern"},
+	}
+	for _, tt := range tests {
+		program, err := parser.Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Compile(program, nil)
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if err.Error() != tt.msg {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
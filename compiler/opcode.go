@@ -0,0 +1,162 @@
+package compiler
+
+import "fmt"
+
+type Op byte
+
+const (
+	OpConst       Op = iota // push Consts[A]
+	OpNil                   // push nil
+	OpPop                   // discard top
+	OpDup                   // duplicate top
+	OpLoadLocal             // push Locals[A]
+	OpStoreLocal            // pop into Locals[A]
+	OpLoadOuter             // push local B of the A-th enclosing function
+	OpStoreOuter            // pop into local B of the A-th enclosing function
+	OpLoadGlobal            // push global Names[A]
+	OpStoreGlobal           // pop into global Names[A]
+	OpAdd                   // binary operators pop right and left, push result
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpIntDiv
+	OpEqual
+	OpLess
+	OpLessEq
+	OpNotEqual
+	OpGreater
+	OpGreaterEq
+	OpNeg              // negate top
+	OpNot              // invert bool on top
+	OpJump             // jump to A
+	OpJumpIfFalse      // pop bool, jump to A if false
+	OpJumpIfFalseOrPop // jump to A keeping top if it is false, pop otherwise (`and`)
+	OpJumpIfTrueOrPop  // jump to A keeping top if it is true, pop otherwise (`or`)
+	OpJumpIfNil        // jump to A keeping top if it is nil (`?.`)
+	OpExpectBool       // check top is bool, A is 0 for `and`, 1 for `or`
+	OpIter             // pop value, push iterator over it
+	OpNext             // advance iterator on top, push key if B is 1 and value, or jump to A at the end
+	OpMatch            // pop values of expressions of Patterns[A] and value, and push values bound by the pattern if it matches, or keep value and jump to B
+	OpList             // pop A values, push list of them
+	OpAppend           // pop value, append to list on top
+	OpExtend           // pop list, append its elements to list on top
+	OpRecord           // push empty record
+	OpSetKey           // pop value, set field Names[A] of record on top
+	OpMerge            // pop record, copy its fields into record on top
+	OpField            // pop record, push its field Names[A], or nil if B is 1 and the field is missing
+	OpSetField         // pop value and record, set field Names[A]
+	OpIndex            // pop index and value, push element
+	OpSetIndex         // pop value, index and list, set element
+	OpSlice            // pop end if A&SliceEnd, start if A&SliceStart, and value, push slice
+	OpConcat           // pop A values, push concatenation of their string forms
+	OpClosure          // push closure of Protos[A]
+	OpCall             // pop A args and function, push result
+	OpReturn           // pop value and return it
+	OpTry              // jump to A with the error pushed if an error is raised until OpEndTry
+	OpEndTry           // stop catching errors by the last OpTry
+	OpThrow            // pop error or message and raise it
+)
+
+// flags of OpSlice
+const (
+	SliceStart = 1 << iota
+	SliceEnd
+)
+
+var opNames = [...]string{
+	OpConst:            "Const",
+	OpNil:              "Nil",
+	OpPop:              "Pop",
+	OpDup:              "Dup",
+	OpLoadLocal:        "LoadLocal",
+	OpStoreLocal:       "StoreLocal",
+	OpLoadOuter:        "LoadOuter",
+	OpStoreOuter:       "StoreOuter",
+	OpLoadGlobal:       "LoadGlobal",
+	OpStoreGlobal:      "StoreGlobal",
+	OpAdd:              "Add",
+	OpSub:              "Sub",
+	OpMul:              "Mul",
+	OpDiv:              "Div",
+	OpMod:              "Mod",
+	OpIntDiv:           "IntDiv",
+	OpEqual:            "Equal",
+	OpLess:             "Less",
+	OpLessEq:           "LessEq",
+	OpNotEqual:         "NotEqual",
+	OpGreater:          "Greater",
+	OpGreaterEq:        "GreaterEq",
+	OpNeg:              "Neg",
+	OpNot:              "Not",
+	OpJump:             "Jump",
+	OpJumpIfFalse:      "JumpIfFalse",
+	OpJumpIfFalseOrPop: "JumpIfFalseOrPop",
+	OpJumpIfTrueOrPop:  "JumpIfTrueOrPop",
+	OpJumpIfNil:        "JumpIfNil",
+	OpExpectBool:       "ExpectBool",
+	OpIter:             "Iter",
+	OpNext:             "Next",
+	OpMatch:            "Match",
+	OpList:             "List",
+	OpAppend:           "Append",
+	OpExtend:           "Extend",
+	OpRecord:           "Record",
+	OpSetKey:           "SetKey",
+	OpMerge:            "Merge",
+	OpField:            "Field",
+	OpSetField:         "SetField",
+	OpIndex:            "Index",
+	OpSetIndex:         "SetIndex",
+	OpSlice:            "Slice",
+	OpConcat:           "Concat",
+	OpClosure:          "Closure",
+	OpCall:             "Call",
+	OpReturn:           "Return",
+	OpTry:              "Try",
+	OpEndTry:           "EndTry",
+	OpThrow:            "Throw",
+}
+
+func (op Op) String() string {
+	if int(op) < len(opNames) && opNames[op] != "" {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", op)
+}
+
+// stackEffect returns how many values in changes the stack size by.
+func stackEffect(in Instr) int {
+	switch in.Op {
+	case OpConst, OpNil, OpDup, OpLoadLocal, OpLoadOuter, OpLoadGlobal, OpRecord, OpClosure:
+		return 1
+	case OpPop, OpStoreLocal, OpStoreOuter, OpStoreGlobal,
+		OpAdd, OpSub, OpMul, OpDiv, OpMod, OpIntDiv, OpEqual, OpLess, OpLessEq, OpNotEqual, OpGreater, OpGreaterEq,
+		OpJumpIfFalse, OpJumpIfFalseOrPop, OpJumpIfTrueOrPop,
+		OpAppend, OpExtend, OpSetKey, OpMerge, OpIndex, OpReturn, OpThrow:
+		return -1
+	case OpMatch:
+		// and values of expressions are popped and bound values are pushed, which the compiler counts
+		return -1
+	case OpNext:
+		return 1 + in.B
+	case OpSetField:
+		return -2
+	case OpSetIndex:
+		return -3
+	case OpList, OpConcat:
+		return 1 - in.A
+	case OpCall:
+		return -in.A
+	case OpSlice:
+		n := 0
+		if in.A&SliceStart != 0 {
+			n--
+		}
+		if in.A&SliceEnd != 0 {
+			n--
+		}
+		return n
+	}
+	return 0
+}
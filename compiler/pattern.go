@@ -0,0 +1,245 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fj68/vvlang/ast"
+)
+
+type PatternKind int
+
+const (
+	PatValue    PatternKind = iota // equal to Consts[Const]
+	PatWildcard                    // anything, i.e. `_`
+	PatBind                        // anything, bound to Name
+	PatList                        // list of Elems, and the rest if Rest is not nil
+	PatRecord                      // record having fields Keys matching Elems, and the rest if Rest is not nil
+	PatAny                         // any of Elems, i.e. `case a, b`
+	PatExpr                        // equal to the value of Expr-th expression of the case
+)
+
+// Pattern is a compiled pattern of a `case` arm.
+// Values of expressions in it are pushed before matching, in the order of Expr.
+// Matching it pushes values bound to names in the order of Bindings.
+type Pattern struct {
+	Kind  PatternKind
+	Const int
+	Expr  int
+	Name  string
+	Elems []*Pattern
+	Keys  []string
+	// Rest is either PatWildcard or PatBind.
+	Rest *Pattern
+}
+
+func (p *Pattern) String() string {
+	switch p.Kind {
+	case PatValue:
+		return fmt.Sprintf("const %d", p.Const)
+	case PatExpr:
+		return fmt.Sprintf("expr %d", p.Expr)
+	case PatWildcard:
+		return "_"
+	case PatBind:
+		return p.Name
+	case PatList:
+		var elems []string
+		for _, e := range p.Elems {
+			elems = append(elems, e.String())
+		}
+		if p.Rest != nil {
+			elems = append(elems, "..."+p.Rest.String())
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+	case PatRecord:
+		var fields []string
+		for i, e := range p.Elems {
+			fields = append(fields, fmt.Sprintf("%s = %s", p.Keys[i], e))
+		}
+		if p.Rest != nil {
+			fields = append(fields, "..."+p.Rest.String())
+		}
+		return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+	case PatAny:
+		var alts []string
+		for _, e := range p.Elems {
+			alts = append(alts, e.String())
+		}
+		return strings.Join(alts, " | ")
+	}
+	return "unknown"
+}
+
+// Bindings returns names bound by p in order.
+func (p *Pattern) Bindings() []string {
+	var names []string
+	switch p.Kind {
+	case PatBind:
+		names = append(names, p.Name)
+	case PatList, PatRecord:
+		for _, e := range p.Elems {
+			names = append(names, e.Bindings()...)
+		}
+		if p.Rest != nil {
+			names = append(names, p.Rest.Bindings()...)
+		}
+	}
+	return names
+}
+
+// Exprs returns the number of expressions in p.
+func (p *Pattern) Exprs() int {
+	switch p.Kind {
+	case PatExpr:
+		return 1
+	case PatList, PatRecord, PatAny:
+		n := 0
+		for _, e := range p.Elems {
+			n += e.Exprs()
+		}
+		return n
+	}
+	return 0
+}
+
+// compilePatterns compiles patterns of a case, returning expressions in them to be evaluated before matching.
+func (c *compiler) compilePatterns(fs *funcState, patterns []ast.Expr) (*Pattern, []ast.Expr, error) {
+	var exprs []ast.Expr
+	if len(patterns) == 1 {
+		p, err := c.compilePattern(fs, patterns[0], &exprs)
+		return p, exprs, err
+	}
+	alts := &Pattern{Kind: PatAny}
+	for _, expr := range patterns {
+		p, err := c.compilePattern(fs, expr, &exprs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if 0 < len(p.Bindings()) {
+			return nil, nil, c.errorf(expr.Span(), "pattern in a case of multiple patterns must not bind names")
+		}
+		alts.Elems = append(alts.Elems, p)
+	}
+	return alts, exprs, nil
+}
+
+// compilePattern compiles expr as a pattern, appending expressions compared by value to exprs.
+// Names in scope are such expressions, while new names are bound.
+func (c *compiler) compilePattern(fs *funcState, expr ast.Expr, exprs *[]ast.Expr) (*Pattern, error) {
+	switch v := expr.(type) {
+	case *ast.NilLiteralExpr:
+		return &Pattern{Kind: PatValue, Const: fs.constant(nil)}, nil
+	case *ast.BoolLiteralExpr:
+		return &Pattern{Kind: PatValue, Const: fs.constant(v.Value)}, nil
+	case *ast.IntLiteralExpr:
+		return &Pattern{Kind: PatValue, Const: fs.constant(v.Value)}, nil
+	case *ast.NumberLiteralExpr:
+		return &Pattern{Kind: PatValue, Const: fs.constant(v.Value)}, nil
+	case *ast.StringLiteralExpr:
+		return &Pattern{Kind: PatValue, Const: fs.constant(v.Value)}, nil
+	case *ast.PrefixExpr:
+		if v.Op == "-" {
+			switch n := v.Right.(type) {
+			case *ast.IntLiteralExpr:
+				return &Pattern{Kind: PatValue, Const: fs.constant(-n.Value)}, nil
+			case *ast.NumberLiteralExpr:
+				return &Pattern{Kind: PatValue, Const: fs.constant(-n.Value)}, nil
+			}
+		}
+	case *ast.VarRefExpr:
+		if v.Name != "_" && c.inScope(fs, v.Name) {
+			break
+		}
+		return c.compileNamePattern(v.Name), nil
+	case *ast.ListLiteralExpr:
+		p := &Pattern{Kind: PatList}
+		for i, elem := range v.Elements {
+			if spread, ok := elem.(*ast.SpreadExpr); ok {
+				if i != len(v.Elements)-1 {
+					return nil, c.errorf(spread.Span(), "rest of list pattern must be the last")
+				}
+				rest, err := c.compileRestPattern(spread.Expr)
+				if err != nil {
+					return nil, err
+				}
+				p.Rest = rest
+				break
+			}
+			e, err := c.compilePattern(fs, elem, exprs)
+			if err != nil {
+				return nil, err
+			}
+			p.Elems = append(p.Elems, e)
+		}
+		return p, nil
+	case *ast.RecordLiteralExpr:
+		p := &Pattern{Kind: PatRecord}
+		for i, elem := range v.Elements {
+			switch e := elem.(type) {
+			case *ast.RecordField:
+				field, err := c.compilePattern(fs, e.Value, exprs)
+				if err != nil {
+					return nil, err
+				}
+				p.Keys = append(p.Keys, e.Key)
+				p.Elems = append(p.Elems, field)
+			case *ast.RecordSpread:
+				if i != len(v.Elements)-1 {
+					return nil, c.errorf(e.Expr.Span(), "rest of record pattern must be the last")
+				}
+				rest, err := c.compileRestPattern(e.Expr)
+				if err != nil {
+					return nil, err
+				}
+				p.Rest = rest
+			}
+		}
+		return p, nil
+	}
+	*exprs = append(*exprs, expr)
+	return &Pattern{Kind: PatExpr, Expr: len(*exprs) - 1}, nil
+}
+
+func (c *compiler) compileNamePattern(name string) *Pattern {
+	if name == "_" {
+		return &Pattern{Kind: PatWildcard}
+	}
+	return &Pattern{Kind: PatBind, Name: name}
+}
+
+// compileRestPattern compiles the pattern after `...`, which is a name or `_`.
+func (c *compiler) compileRestPattern(expr ast.Expr) (*Pattern, error) {
+	v, ok := expr.(*ast.VarRefExpr)
+	if !ok {
+		return nil, c.errorf(expr.Span(), "rest of pattern must be a name")
+	}
+	return c.compileNamePattern(v.Name), nil
+}
+
+// patternNames returns names bound by the pattern expr, to declare them as locals beforehand.
+func patternNames(expr ast.Expr) []string {
+	var names []string
+	switch v := expr.(type) {
+	case *ast.VarRefExpr:
+		if v.Name != "_" {
+			names = append(names, v.Name)
+		}
+	case *ast.SpreadExpr:
+		names = append(names, patternNames(v.Expr)...)
+	case *ast.ListLiteralExpr:
+		for _, elem := range v.Elements {
+			names = append(names, patternNames(elem)...)
+		}
+	case *ast.RecordLiteralExpr:
+		for _, elem := range v.Elements {
+			switch e := elem.(type) {
+			case *ast.RecordField:
+				names = append(names, patternNames(e.Value)...)
+			case *ast.RecordSpread:
+				names = append(names, patternNames(e.Expr)...)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,87 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fj68/vvlang/lexer"
+)
+
+type Instr struct {
+	Op Op
+	A  int
+	B  int
+}
+
+func (in Instr) String() string {
+	switch in.Op {
+	case OpLoadOuter, OpStoreOuter, OpNext, OpMatch:
+		return fmt.Sprintf("%s %d %d", in.Op, in.A, in.B)
+	case OpConst, OpLoadLocal, OpStoreLocal, OpLoadGlobal, OpStoreGlobal,
+		OpJump, OpJumpIfFalse, OpJumpIfFalseOrPop, OpJumpIfTrueOrPop, OpJumpIfNil, OpExpectBool,
+		OpList, OpSetKey, OpField, OpSetField, OpSlice, OpConcat, OpClosure, OpCall, OpTry:
+		return fmt.Sprintf("%s %d", in.Op, in.A)
+	}
+	return in.Op.String()
+}
+
+// Proto is a compiled function, or the top-level of a program.
+type Proto struct {
+	Name string
+	// Params is the number of parameters, which occupy the first local slots.
+	Params int
+	// Locals holds names of local slots.
+	Locals []string
+	// MaxStack is the maximum depth of the operand stack.
+	MaxStack int
+	Code     []Instr
+	// Pos holds the source position of each instruction.
+	Pos []lexer.Pos
+	// Consts holds constants, each of which is int64, float64, string, bool or nil.
+	Consts []any
+	// Names holds names of globals and record fields.
+	Names  []string
+	Protos []*Proto
+	// Patterns holds patterns of `case` arms.
+	Patterns []*Pattern
+}
+
+// Disassemble returns a human readable listing of the code of proto and its nested functions.
+func (proto *Proto) Disassemble() string {
+	var b strings.Builder
+	proto.disassemble(&b)
+	return b.String()
+}
+
+func (proto *Proto) disassemble(b *strings.Builder) {
+	name := proto.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	fmt.Fprintf(b, "fun %s params=%d locals=[%s]\n", name, proto.Params, strings.Join(proto.Locals, ", "))
+	for pc, in := range proto.Code {
+		fmt.Fprintf(b, "%4d %s", pc, in)
+		switch in.Op {
+		case OpConst:
+			fmt.Fprintf(b, " (%#v)", proto.Consts[in.A])
+		case OpLoadGlobal, OpStoreGlobal, OpSetKey, OpField, OpSetField:
+			fmt.Fprintf(b, " (%s)", proto.Names[in.A])
+		case OpMatch:
+			fmt.Fprintf(b, " (%s)", proto.Patterns[in.A])
+		}
+		b.WriteRune('\n')
+	}
+	for _, p := range proto.Protos {
+		p.disassemble(b)
+	}
+}
+
+// Error is an error found while compiling a program.
+type Error struct {
+	Pos lexer.Pos
+	Msg string
+}
+
+func (err *Error) Error() string {
+	return err.Msg
+}
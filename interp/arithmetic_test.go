@@ -0,0 +1,155 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return 7 - 2", VInt(5)},
+		{"return 3 * 4", VInt(12)},
+		{"return 9 / 2", VNumber(4.5)},
+		{"return 8 / 2", VNumber(4)},
+		{"return 7 mod 3", VInt(1)},
+		{"return -7 mod 3", VInt(-1)},
+		{"return 9 div 2", VInt(4)},
+		{"return -9 div 2", VInt(-4)},
+		{"return 7.5 div 2", VNumber(3)},
+		{"return 7.5 mod 2", VNumber(1.5)},
+		{"return 1 + 2 * 3", VInt(7)},
+		{"return 10 - 4 - 3", VInt(3)},
+		{"return 2 * 3 mod 4", VInt(2)},
+		{"return 1 + 0.5", VNumber(1.5)},
+		{"return 0.5 * 4", VNumber(2)},
+		{"return -(2 - 3)", VInt(1)},
+		{"x = 5 return x * x - x / 5", VNumber(24)},
+		{"return 0xff + 0b101 + 0o17", VInt(255 + 5 + 15)},
+		{"return 1_000_000 * 1_000", VInt(1_000_000_000)},
+		{"return 9007199254740993", VInt(9007199254740993)},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if v != tt.expected {
+			t.Fatalf("%s: expected %#v, got %#v", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	for _, text := range []string{"return 1 / 0", "return 1 mod 0", "return 1 div 0", "return 1.5 div 0"} {
+		s := NewState()
+		err := s.Eval([]rune(text))
+		if !errors.Is(err, ErrDivisionByZero) {
+			t.Fatalf("%s: expected division by zero error, got %v", text, err)
+		}
+	}
+}
+
+func TestArithmeticTypeError(t *testing.T) {
+	s := NewState()
+	if err := s.Eval([]rune("return 'a' * 2")); err == nil {
+		t.Fatal("expected error for non-number operand")
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []string{
+		"return 9223372036854775807 + 1",
+		"return -9223372036854775807 - 2",
+		"return 4294967296 * 4294967296",
+		"return -(-9223372036854775807 - 1)",
+		"return (-9223372036854775807 - 1) div -1",
+	}
+	for _, text := range tests {
+		s := NewState()
+		err := s.Eval([]rune(text))
+		if !errors.Is(err, ErrIntegerOverflow) {
+			t.Fatalf("%s: expected integer overflow error, got %v", text, err)
+		}
+	}
+}
+
+func TestNumberComparison(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected VBool
+	}{
+		{"return 1 == 1.0", true},
+		{"return 1.0 == 1", true},
+		{"return 1 < 1.5", true},
+		{"return 1.5 < 1", false},
+		{"return 2 >= 2.0", true},
+		{"return [1, 2] == [1.0, 2.0]", true},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if v := s.RetVals.Pop(); v != tt.expected {
+			t.Fatalf("%s: expected %v, got %v", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestIntegerResults(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return [10, 20, 30][1.0]", VInt(20)},
+		{"return [10, 20, 30][6 / 3]", VInt(30)},
+		{"return floor(2.7)", VInt(2)},
+		{"return ceil(2.1)", VInt(3)},
+		{"return floor(-2.5)", VInt(-3)},
+		{"return floor(5)", VInt(5)},
+		{"return number('9007199254740993')", VInt(9007199254740993)},
+		{"return number('1.5')", VNumber(1.5)},
+		{"return len('abc')", VInt(3)},
+		{"return list(range(2))[1]", VInt(1)},
+		{"return string(9007199254740993)", VString("9007199254740993")},
+		{`return "{type(1)} {type(1.5)} {type(1 + 0.5)} {type(3 div 2)}"`, VString("int number number int")},
+		{"return contains([1, 2], 2.0)", VBool(true)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapMath))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if v := s.RetVals.Pop(); v != tt.expected {
+			t.Fatalf("%s: expected %#v, got %#v", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestNonIntegralIndex(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"return [1, 2][0.5]", "list index must be an integer, got 0.5"},
+		{"x = [1, 2]\nx[1.5] = 0", "list index must be an integer, got 1.5"},
+		{"return 'ab'[0.1]", "string index must be an integer, got 0.1"},
+		{"return [1, 2][0.5:]", "slice start must be an integer, got 0.5"},
+		{"return [1, 2][:1 / 3]", "slice end must be an integer, got 0.333333"},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error, got nil", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("%s: expected %q, got %q", tt.text, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package interp
+
+import "testing"
+
+func TestFieldAssign(t *testing.T) {
+	s := NewState()
+	text := `fun move_by(sprite, dx, dy)
+  sprite.x = sprite.x + dx
+  sprite.y = sprite.y + dy
+end
+player = { x = 0, y = 0 }
+move_by(player, 3, 4)
+player.name = 'player'
+return player`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	r, ok := v.(*VRecord)
+	if !ok {
+		t.Fatalf("expected *VRecord, got %T", v)
+	}
+	expected := &VRecord{Fields: map[string]Value{
+		"x":    VNumber(3),
+		"y":    VNumber(4),
+		"name": VString("player"),
+	}}
+	if eq, _ := r.Equal(expected); !eq {
+		t.Fatalf("expected %s, got %s", expected, r)
+	}
+}
+
+func TestIndexAssign(t *testing.T) {
+	s := NewState()
+	text := "xs = [1, 2, 3] xs[0] = 10 xs[-1] = 30 return xs"
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	list, ok := v.(*VList)
+	if !ok {
+		t.Fatalf("expected *VList, got %T", v)
+	}
+	expected := &VList{Elements: []Value{VNumber(10), VNumber(2), VNumber(30)}}
+	if eq, _ := list.Equal(expected); !eq {
+		t.Fatalf("expected %s, got %s", expected, list)
+	}
+}
+
+func TestNestedAssign(t *testing.T) {
+	s := NewState()
+	text := "a = { b = [0, 1, { c = 0 }] } a.b[2].c = 1 a.b[0] = 'x' return a"
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	if v.String() != `{b = ["x", 1, {c = 1}]}` {
+		t.Fatalf("unexpected result: %s", v)
+	}
+}
+
+func TestAssignIsShared(t *testing.T) {
+	s := NewState()
+	text := "xs = [0] ys = xs ys[0] = 1 return xs[0]"
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	if n, ok := v.(VInt); !ok || n != 1 {
+		t.Fatalf("expected 1, got %v", v)
+	}
+}
+
+func TestAssignToSliceDoesNotModifyOriginal(t *testing.T) {
+	s := NewState()
+	text := "xs = [0, 1, 2] ys = xs[1:] ys[0] = 10 return xs"
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	if v.String() != "[0, 1, 2]" {
+		t.Fatalf("expected [0, 1, 2], got %s", v)
+	}
+}
+
+func TestAssignErrors(t *testing.T) {
+	tests := []string{
+		"xs = [1, 2] xs[2] = 0",
+		"xs = [1, 2] xs[-3] = 0",
+		"xs = [1, 2] xs['a'] = 0",
+		"x = 1 x.y = 0",
+		"s = 'abc' s[0] = 'x'",
+	}
+	for _, text := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
+
+func TestCyclicValues(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"r = {a = 1} r.self = r return string(r)", VString("{a = 1, self = {...}}")},
+		{"xs = [1, 2] xs[1] = xs return string(xs)", VString("[1, [...]]")},
+		{"r = {xs = []} push(r.xs, r) return string(r)", VString("{xs = [{...}]}")},
+		// values shared without a cycle are formatted fully
+		{"x = [1] return string([x, x])", VString("[[1], [1]]")},
+		{"a = {} a.self = a b = {} b.self = b return a == b", VBool(true)},
+		{"a = {n = 1} a.self = a b = {n = 2} b.self = b return a == b", VBool(false)},
+		{"xs = [1] xs[0] = xs return xs == xs", VBool(true)},
+		{"xs = [1] xs[0] = xs ys = [1] ys[0] = ys return [xs] == [ys]", VBool(true)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if v := s.RetVals.Pop(); v != tt.expected {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+
+	// interpolation formats values as string() does
+	s := NewState()
+	if err := s.Eval([]rune(`xs = [] xs = [xs] xs[0] = xs return "{xs}"`)); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.RetVals.Pop(); v != VString("[[...]]") {
+		t.Fatalf("expected [[...]], but got %s", v)
+	}
+}
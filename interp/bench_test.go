@@ -0,0 +1,99 @@
+package interp
+
+import "testing"
+
+func benchmarkEval(b *testing.B, text string) {
+	b.Helper()
+	for i := 0; i < b.N; i++ {
+		s := NewState()
+		s.RegisterGlobals(DefaultBuiltins)
+		if err := s.Eval([]rune(text)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWhileLoop(b *testing.B) {
+	benchmarkEval(b, `fun sum(n)
+  i = 0
+  total = 0
+  while i < n
+    i = i + 1
+    if i mod 3 == 0
+      continue
+    end
+    total = total + i
+  end
+  return total
+end
+return sum(100000)`)
+}
+
+func BenchmarkTopLevelWhileLoop(b *testing.B) {
+	benchmarkEval(b, `i = 0
+total = 0
+while i < 100000
+  i = i + 1
+  total = total + i
+end
+return total`)
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkEval(b, `fun fib(n)
+  if n < 2
+    return n
+  end
+  return fib(n - 1) + fib(n - 2)
+end
+return fib(20)`)
+}
+
+func BenchmarkClosureCounter(b *testing.B) {
+	benchmarkEval(b, `fun make_counter()
+  n = 0
+  return fun()
+    n = n + 1
+    return n
+  end
+end
+c = make_counter()
+fun run(times)
+  i = 0
+  while i < times
+    c()
+    i = i + 1
+  end
+  return c()
+end
+return run(50000)`)
+}
+
+func BenchmarkListAndRecord(b *testing.B) {
+	benchmarkEval(b, `fun run(n)
+  xs = [0, 0, 0, 0, 0, 0, 0, 0, 0, 0]
+  p = { x = 0, y = 0 }
+  i = 0
+  while i < n
+    xs[i mod 10] = xs[i mod 10] + 1
+    p.x = p.x + xs[i mod 10]
+    p.y = len(xs) + p.y
+    i = i + 1
+  end
+  return p
+end
+return run(50000)`)
+}
+
+func BenchmarkStringInterpolation(b *testing.B) {
+	benchmarkEval(b, `fun run(n)
+  i = 0
+  s = ''
+  while i < n
+    s = "{i}:{i * 2}"
+    i = i + 1
+  end
+  return s
+end
+return run(20000)`)
+}
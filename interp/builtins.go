@@ -3,21 +3,19 @@ package interp
 import (
 	"fmt"
 	"math"
+	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/fj68/vvlang/value"
 )
 
-var DefaultBuiltins = map[string]Value{
-	"not":    VBuiltinFun(builtinNot),
-	"print":  VBuiltinFun(builtinPrint),
-	"type":   VBuiltinFun(builtinType),
-	"bool":   VBuiltinFun(builtinBool),
-	"number": VBuiltinFun(builtinNumber),
-	"ceil":   VBuiltinFun(builtinCeil),
-	"floor":  VBuiltinFun(builtinFloor),
-	"string": VBuiltinFun(builtinString),
-	"len":    VBuiltinFun(builtinLen),
-}
+// DefaultBuiltins holds builtins of pure capabilities, which cannot access anything outside the State,
+// and print() of io, which it has held since before capabilities.
+var DefaultBuiltins = union(capabilities[CapCore], capabilities[CapMath], capabilities[CapString], map[string]Value{
+	"print": VBuiltinFun(builtinPrint),
+})
 
 func builtinNot(s *State, args []Value) (Value, error) {
 	if len(args) != 1 {
@@ -53,6 +51,8 @@ func builtinBool(s *State, args []Value) (Value, error) {
 	switch v := args[0].(type) {
 	case VBool:
 		return v, nil
+	case VInt:
+		return VBool(v != 0), nil
 	case VNumber:
 		return VBool(v != 0), nil
 	case VString:
@@ -65,6 +65,12 @@ func builtinBool(s *State, args []Value) (Value, error) {
 		return VBool(len(v.Elements) != 0), nil
 	case *VRecord:
 		return VBool(len(v.Fields) != 0), nil
+	case VRange:
+		return VBool(v.Len() != 0), nil
+	case VNil:
+		return VBool(false), nil
+	case *VError:
+		return VBool(true), nil
 	}
 	return nil, fmt.Errorf("unknown value type: %s", args[0].Type().String())
 }
@@ -76,12 +82,18 @@ func builtinNumber(s *State, args []Value) (Value, error) {
 	switch v := args[0].(type) {
 	case VBool:
 		if v {
-			return VNumber(1), nil
+			return VInt(1), nil
 		}
-		return VNumber(0), nil
+		return VInt(0), nil
+	case VInt:
+		return v, nil
 	case VNumber:
 		return v, nil
 	case VString:
+		// integers stay exact, e.g. large IDs
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return VInt(n), nil
+		}
 		n, err := strconv.ParseFloat(string(v), 64)
 		if err != nil {
 			return nil, err
@@ -95,53 +107,92 @@ func builtinNumber(s *State, args []Value) (Value, error) {
 		return nil, fmt.Errorf("unable to convert list to number")
 	case *VRecord:
 		return nil, fmt.Errorf("unable to convert record to number")
+	case VRange:
+		return nil, fmt.Errorf("unable to convert range to number")
+	case VNil:
+		return nil, fmt.Errorf("unable to convert nil to number")
+	case *VError:
+		return nil, fmt.Errorf("unable to convert error to number")
 	}
 	return nil, fmt.Errorf("unknown value type: %s", args[0].Type().String())
 }
 
+// builtinList makes a new list of elements of a list or a range, or of characters of a string.
+func builtinList(s *State, args []Value) (Value, error) {
+	if err := expectArgs("list", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if str, ok := args[0].(VString); ok {
+		return s.newStringList(strings.Split(string(str), ""))
+	}
+	elements, err := elementsArg(s, "list", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	// copy so that modifying the new list does not change the original
+	return s.newList(append([]Value(nil), elements...))
+}
+
+// builtinError makes an error to throw, e.g. error('not found', 'lookup'), whose kind is "error" unless given.
+func builtinError(s *State, args []Value) (Value, error) {
+	if err := expectArgs("error", args, 1, 2); err != nil {
+		return nil, err
+	}
+	message, err := stringArg("error", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	kind := ErrorKindThrown
+	if len(args) == 2 {
+		kind, err = stringArg("error", args, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &VError{Message: message, Kind: kind}, nil
+}
+
 func builtinCeil(s *State, args []Value) (Value, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("too many / less arguments for ceil()")
 	}
-	v, ok := args[0].(VNumber)
-	if !ok {
-		return nil, fmt.Errorf("argument for ceil() is expected number, but got %s", v.Type())
-	}
-	return VNumber(math.Ceil(float64(v))), nil
+	return roundToInt("ceil", args[0], math.Ceil)
 }
 
 func builtinFloor(s *State, args []Value) (Value, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("too many / less arguments for floor()")
 	}
-	v, ok := args[0].(VNumber)
-	if !ok {
-		return nil, fmt.Errorf("argument for floor() is expected number, but got %s", v.Type())
+	return roundToInt("floor", args[0], math.Floor)
+}
+
+// roundToInt rounds v by round for the builtin name, returning an int.
+func roundToInt(name string, v Value, round func(float64) float64) (Value, error) {
+	switch n := v.(type) {
+	case VInt:
+		return n, nil
+	case VNumber:
+		f := round(float64(n))
+		if math.IsNaN(f) || f < math.MinInt64 || math.MaxInt64 <= f {
+			return nil, fmt.Errorf("result of %s() is out of range of int: %g", name, f)
+		}
+		return VInt(f), nil
 	}
-	return VNumber(math.Floor(float64(v))), nil
+	return nil, fmt.Errorf("argument for %s() is expected number, but got %s", name, v.Type())
 }
 
 func builtinString(s *State, args []Value) (Value, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("too many / less arguments for string()")
 	}
-	switch v := args[0].(type) {
-	case VBool:
-		return VString(fmt.Sprintf("%t", v)), nil
-	case VNumber:
-		return VString(fmt.Sprintf("%g", v)), nil
-	case VString:
-		return v, nil
-	case *VUserFun:
-		return VString(v.Type().String()), nil
-	case VBuiltinFun:
-		return VString(v.Type().String()), nil
-	case *VList:
-		return VString(v.String()), nil
-	case *VRecord:
-		return VString(v.String()), nil
+	str, err := value.ToString(args[0])
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("unknown value type: %s", args[0].Type().String())
+	if err := s.alloc(len(str)); err != nil {
+		return nil, err
+	}
+	return str, nil
 }
 
 func builtinLen(s *State, args []Value) (Value, error) {
@@ -151,18 +202,149 @@ func builtinLen(s *State, args []Value) (Value, error) {
 	switch v := args[0].(type) {
 	case VBool:
 		return nil, fmt.Errorf("argument for len() is expected string or array, but got bool")
-	case VNumber:
-		return nil, fmt.Errorf("argument for len() is expected string or array, but got number")
+	case VInt, VNumber:
+		return nil, fmt.Errorf("argument for len() is expected string or array, but got %s", v.Type())
 	case VString:
-		return VNumber(len([]rune(v))), nil
+		return VInt(len([]rune(v))), nil
 	case *VList:
-		return VNumber(len(v.Elements)), nil
+		return VInt(len(v.Elements)), nil
 	case *VRecord:
-		return VNumber(len(v.Fields)), nil
+		return VInt(len(v.Fields)), nil
+	case VRange:
+		return VInt(v.Len()), nil
 	case *VUserFun:
 		return nil, fmt.Errorf("argument for len() is expected string or array, but got fun")
 	case VBuiltinFun:
 		return nil, fmt.Errorf("argument for len() is expected string or array, but got fun")
+	case VNil, *VError:
+		return nil, fmt.Errorf("argument for len() is expected string or array, but got %s", v.Type())
 	}
 	return nil, fmt.Errorf("unknown value type: %s", args[0].Type().String())
 }
+
+func builtinReadFile(s *State, args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("too many / less arguments for read_file()")
+	}
+	path, ok := args[0].(VString)
+	if !ok {
+		return nil, fmt.Errorf("argument for read_file() is expected string, but got %s", args[0].Type())
+	}
+	text, err := os.ReadFile(string(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(text)); err != nil {
+		return nil, err
+	}
+	return VString(text), nil
+}
+
+func builtinWriteFile(s *State, args []Value) (Value, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("too many / less arguments for write_file()")
+	}
+	path, ok := args[0].(VString)
+	if !ok {
+		return nil, fmt.Errorf("first argument for write_file() is expected string, but got %s", args[0].Type())
+	}
+	text, ok := args[1].(VString)
+	if !ok {
+		return nil, fmt.Errorf("second argument for write_file() is expected string, but got %s", args[1].Type())
+	}
+	return nil, os.WriteFile(string(path), []byte(text), 0644)
+}
+
+func builtinEnv(s *State, args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("too many / less arguments for env()")
+	}
+	name, ok := args[0].(VString)
+	if !ok {
+		return nil, fmt.Errorf("argument for env() is expected string, but got %s", args[0].Type())
+	}
+	return VString(os.Getenv(string(name))), nil
+}
+
+func builtinNow(s *State, args []Value) (Value, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("too many arguments for now()")
+	}
+	return VNumber(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+}
+
+func builtinSleep(s *State, args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("too many / less arguments for sleep()")
+	}
+	sec, ok := value.ToFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("argument for sleep() is expected number, but got %s", args[0].Type())
+	}
+	d := time.Duration(sec * float64(time.Second))
+	ctx := s.machine.Context
+	if ctx == nil {
+		time.Sleep(d)
+		return nil, nil
+	}
+	// wake up when evaluation is cancelled
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+var ordinals = []string{"first", "second", "third"}
+
+// expectArgs checks the number of args of the builtin name is between min and max.
+func expectArgs(name string, args []Value, min int, max int) error {
+	if len(args) < min || max < len(args) {
+		return fmt.Errorf("too many / less arguments for %s()", name)
+	}
+	return nil
+}
+
+// argLabel names the i-th argument of the builtin name in error messages.
+func argLabel(name string, args []Value, i int) string {
+	if len(args) == 1 {
+		return fmt.Sprintf("argument for %s()", name)
+	}
+	if len(ordinals) <= i {
+		return fmt.Sprintf("argument %d for %s()", i+1, name)
+	}
+	return fmt.Sprintf("%s argument for %s()", ordinals[i], name)
+}
+
+func argError(name string, args []Value, i int, expected string) error {
+	return fmt.Errorf("%s is expected %s, but got %s", argLabel(name, args, i), expected, args[i].Type())
+}
+
+func stringArg(name string, args []Value, i int) (string, error) {
+	str, ok := args[i].(VString)
+	if !ok {
+		return "", argError(name, args, i, "string")
+	}
+	return string(str), nil
+}
+
+// intArg returns the argument as int, which may be a float without fraction.
+// Builtins building values of the size count it by alloc, so that the size is limited by the host.
+func intArg(name string, args []Value, i int) (int, error) {
+	switch n := args[i].(type) {
+	case VInt:
+		// int is 32 bits on some platforms
+		if int64(int(n)) == int64(n) {
+			return int(n), nil
+		}
+	case VNumber:
+		// float64(math.MaxInt) is rounded up out of int
+		if f := float64(n); f == math.Trunc(f) && math.MinInt <= f && f < math.MaxInt {
+			return int(f), nil
+		}
+	}
+	return 0, argError(name, args, i, "int")
+}
@@ -0,0 +1,349 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+var funBuiltins = map[string]Value{
+	"map":       VBuiltinFun(builtinMap),
+	"filter":    VBuiltinFun(builtinFilter),
+	"reduce":    VBuiltinFun(builtinReduce),
+	"each":      VBuiltinFun(builtinEach),
+	"any":       VBuiltinFun(builtinAny),
+	"all":       VBuiltinFun(builtinAll),
+	"find":      VBuiltinFun(builtinFind),
+	"zip":       VBuiltinFun(builtinZip),
+	"enumerate": VBuiltinFun(builtinEnumerate),
+	"range":     VBuiltinFun(builtinRange),
+	"flat_map":  VBuiltinFun(builtinFlatMap),
+	"sort_by":   VBuiltinFun(builtinSortBy),
+}
+
+// elementsArg returns elements of the list or range argument of the builtin name, which must not be modified.
+func elementsArg(s *State, name string, args []Value, i int) ([]Value, error) {
+	switch v := args[i].(type) {
+	case *VList:
+		return v.Elements, nil
+	case VRange:
+		if maxBuiltSize < v.Len() {
+			return nil, fmt.Errorf("range of %d numbers is too long for %s()", v.Len(), name)
+		}
+		// count before building, so that the limit stops huge ranges
+		if err := s.alloc(v.Len()); err != nil {
+			return nil, err
+		}
+		elements := make([]Value, v.Len())
+		for i := range elements {
+			elements[i] = VInt(v.At(i))
+		}
+		return elements, nil
+	}
+	return nil, argError(name, args, i, "list")
+}
+
+// listFunArgs returns the elements and the function arguments of the builtin name, e.g. map(list, f).
+func listFunArgs(s *State, name string, args []Value, min int, max int) ([]Value, Value, error) {
+	if err := expectArgs(name, args, min, max); err != nil {
+		return nil, nil, err
+	}
+	elements, err := elementsArg(s, name, args, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return elements, args[1], nil
+}
+
+// callPredicate calls f of the builtin name with args, which should return bool.
+func callPredicate(s *State, name string, f Value, args ...Value) (bool, error) {
+	v, err := s.callValue(f, args)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(VBool)
+	if !ok {
+		return false, fmt.Errorf("function for %s() is expected to return bool, but got %s", name, typeOf(v))
+	}
+	return bool(b), nil
+}
+
+func (s *State) newList(elements []Value) (Value, error) {
+	list := &VList{Elements: elements}
+	if err := s.allocValue(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func builtinMap(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "map", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	elements := make([]Value, 0, len(xs))
+	for _, elem := range xs {
+		v, err := s.callValue(f, []Value{elem})
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, v)
+	}
+	return s.newList(elements)
+}
+
+func builtinFilter(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "filter", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	var elements []Value
+	for _, elem := range xs {
+		ok, err := callPredicate(s, "filter", f, elem)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			elements = append(elements, elem)
+		}
+	}
+	return s.newList(elements)
+}
+
+func builtinReduce(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "reduce", args, 2, 3)
+	if err != nil {
+		return nil, err
+	}
+	var acc Value
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(xs) == 0 {
+			return nil, fmt.Errorf("reduce() of empty list with no initial value")
+		}
+		acc, xs = xs[0], xs[1:]
+	}
+	for _, elem := range xs {
+		acc, err = s.callValue(f, []Value{acc, elem})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+func builtinEach(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "each", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range xs {
+		if _, err := s.callValue(f, []Value{elem}); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func builtinAny(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "any", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range xs {
+		ok, err := callPredicate(s, "any", f, elem)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return VBool(true), nil
+		}
+	}
+	return VBool(false), nil
+}
+
+func builtinAll(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "all", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range xs {
+		ok, err := callPredicate(s, "all", f, elem)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return VBool(false), nil
+		}
+	}
+	return VBool(true), nil
+}
+
+// builtinFind returns the index of a substring, i.e. find(text, sub), or the first element satisfying the function, or nil,
+// i.e. find(list, f). It is of both core and string, so that either form works with either capability.
+func builtinFind(s *State, args []Value) (Value, error) {
+	if err := expectArgs("find", args, 2, 2); err != nil {
+		return nil, err
+	}
+	switch args[0].(type) {
+	case VString:
+		return findSubstring(args)
+	case *VList, VRange:
+	default:
+		return nil, argError("find", args, 0, "list or string")
+	}
+	xs, f, err := listFunArgs(s, "find", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range xs {
+		ok, err := callPredicate(s, "find", f, elem)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return elem, nil
+		}
+	}
+	return nil, nil
+}
+
+func builtinZip(s *State, args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("too many / less arguments for zip()")
+	}
+	lists := make([]*VList, len(args))
+	n := math.MaxInt
+	for i := range args {
+		list, ok := args[i].(*VList)
+		if !ok {
+			return nil, fmt.Errorf("argument %d for zip() is expected list, but got %s", i+1, typeOf(args[i]))
+		}
+		lists[i] = list
+		if len(list.Elements) < n {
+			n = len(list.Elements)
+		}
+	}
+	// count the tuples, while newList counts the list of them
+	if err := s.alloc(n * (len(lists) + 1)); err != nil {
+		return nil, err
+	}
+	// stop at the end of the shortest list
+	elements := make([]Value, n)
+	for i := range elements {
+		tuple := make([]Value, len(lists))
+		for j, list := range lists {
+			tuple[j] = list.Elements[i]
+		}
+		elements[i] = &VList{Elements: tuple}
+	}
+	return s.newList(elements)
+}
+
+func builtinEnumerate(s *State, args []Value) (Value, error) {
+	if err := expectArgs("enumerate", args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg("enumerate", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	// count the pairs, while newList counts the list of them
+	if err := s.alloc(len(list.Elements) * 3); err != nil {
+		return nil, err
+	}
+	elements := make([]Value, len(list.Elements))
+	for i, elem := range list.Elements {
+		elements[i] = &VList{Elements: []Value{VInt(i), elem}}
+	}
+	return s.newList(elements)
+}
+
+// builtinRange returns numbers from start up to, but not including, end, i.e. range(end), range(start, end) or range(start, end, step).
+func builtinRange(s *State, args []Value) (Value, error) {
+	if err := expectArgs("range", args, 1, 3); err != nil {
+		return nil, err
+	}
+	bounds := []int{0, 0, 1}
+	for i := range args {
+		n, err := intArg("range", args, i)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = n
+	}
+	r := VRange{Start: bounds[0], End: bounds[1], Step: bounds[2]}
+	if len(args) == 1 {
+		r.Start, r.End = 0, bounds[0]
+	}
+	if r.Step == 0 {
+		return nil, fmt.Errorf("step for range() must not be zero")
+	}
+	if math.MaxInt < r.Count() {
+		return nil, fmt.Errorf("range() has too many numbers")
+	}
+	return r, nil
+}
+
+func builtinFlatMap(s *State, args []Value) (Value, error) {
+	xs, f, err := listFunArgs(s, "flat_map", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	var elements []Value
+	for _, elem := range xs {
+		v, err := s.callValue(f, []Value{elem})
+		if err != nil {
+			return nil, err
+		}
+		l, ok := v.(*VList)
+		if !ok {
+			return nil, fmt.Errorf("function for flat_map() is expected to return list, but got %s", typeOf(v))
+		}
+		elements = append(elements, l.Elements...)
+	}
+	return s.newList(elements)
+}
+
+// builtinSortBy sorts the list in place by keys the function returns, calling it once for each element.
+func builtinSortBy(s *State, args []Value) (Value, error) {
+	if err := expectArgs("sort_by", args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg("sort_by", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	f := args[1]
+	type keyed struct {
+		key  Value
+		elem Value
+	}
+	pairs := make([]keyed, len(list.Elements))
+	for i, elem := range list.Elements {
+		key, err := s.callValue(f, []Value{elem})
+		if err != nil {
+			return nil, err
+		}
+		pairs[i] = keyed{key, elem}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var lt bool
+		lt, err = pairs[i].key.LessThan(pairs[j].key)
+		return lt
+	})
+	if err != nil {
+		return nil, err
+	}
+	// f may change the list, so that the sorted elements replace it instead of being written back
+	elements := make([]Value, len(pairs))
+	for i, p := range pairs {
+		elements[i] = p.elem
+	}
+	list.Elements = elements
+	return nil, nil
+}
@@ -0,0 +1,171 @@
+package interp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFunBuiltins(t *testing.T) {
+	pairs := func(xs ...Value) *VList {
+		elements := make([]Value, 0, len(xs)/2)
+		for i := 0; i < len(xs); i += 2 {
+			elements = append(elements, &VList{Elements: []Value{xs[i], xs[i+1]}})
+		}
+		return &VList{Elements: elements}
+	}
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return map([1, 2, 3], fun(x) return x * 2 end)", ints(2, 4, 6)},
+		{"return map([1.5, 2.5], floor)", ints(1, 2)},
+		{"return filter([1, 2, 3, 4], fun(x) return 2 < x end)", ints(3, 4)},
+		{"return filter([], fun(x) return true end)", ints()},
+		{"return reduce([1, 2, 3], fun(a, b) return a + b end)", VInt(6)},
+		{"return reduce([], fun(a, b) return a + b end, 10)", VInt(10)},
+		{"return reduce(['a', 'b'], fun(a, b) return \"{a}{b}\" end, '>')", VString(">ab")},
+		{"sum = [0] each([1, 2, 3], fun(x) sum[0] = sum[0] + x end) return sum[0]", VInt(6)},
+		{"return any([1, 2], fun(x) return 1 < x end)", VBool(true)},
+		{"return any([], fun(x) return true end)", VBool(false)},
+		{"return all([1, 2], fun(x) return 1 < x end)", VBool(false)},
+		{"return all([], fun(x) return false end)", VBool(true)},
+		{"return find([1, 2, 3], fun(x) return 1 < x end)", VInt(2)},
+		{"return zip([1, 2, 3], ['a', 'b'])", pairs(VInt(1), VString("a"), VInt(2), VString("b"))},
+		{"return enumerate(['a', 'b'])", pairs(VInt(0), VString("a"), VInt(1), VString("b"))},
+		{"return list(range(3))", ints(0, 1, 2)},
+		{"return list(range(1, 4))", ints(1, 2, 3)},
+		{"return list(range(0, 10, 4))", ints(0, 4, 8)},
+		{"return list(range(3, 0, -1))", ints(3, 2, 1)},
+		{"return list(range(3, 0))", ints()},
+		{"return range(3)", VRange{Start: 0, End: 3, Step: 1}},
+		{"return [len(range(1, 10, 3)), len(range(0, -10, -3)), len(range(5, 5))]", ints(3, 4, 0)},
+		{"return map(range(1, 4), fun(x) return x * x end)", ints(1, 4, 9)},
+		{"return reduce(range(5), fun(a, b) return a + b end)", VInt(10)},
+		{"xs = [1] ys = list(xs) push(ys, 2) return xs", ints(1)},
+		{"return list('ab')", strs("a", "b")},
+		{"return flat_map([1, 2], fun(x) return [x, x] end)", ints(1, 1, 2, 2)},
+		{"xs = ['bb', 'a', 'cc', 'd'] sort_by(xs, len) return xs", strs("a", "d", "bb", "cc")},
+		{"xs = [3, 1, 2] sort_by(xs, fun(x) return -x end) return xs", ints(3, 2, 1)},
+		// the function changing the list does not break sorting
+		{"xs = [3, 2, 1] sort_by(xs, fun(x) pop(xs) return x end) return xs", ints(1, 2, 3)},
+		{"xs = [2, 1] sort_by(xs, fun(x) push(xs, 0) return x end) return xs", ints(1, 2)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapMath))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestFunBuiltinsFind(t *testing.T) {
+	// find() of core and string is the same, so that both forms work whichever is enabled
+	for _, caps := range [][]Capability{{CapCore, CapString}, {CapString, CapCore}, {CapCore}, {CapString}} {
+		s := NewState(WithCapabilities(caps...))
+		if err := s.Eval([]rune("return [find(['a', 'b'], fun(x) return x == 'b' end), find('ab', 'b')]")); err != nil {
+			t.Fatalf("%v: %s", caps, err)
+		}
+		if v := s.RetVals.Pop(); v.String() != `["b", 1]` {
+			t.Fatalf("%v: expected [\"b\", 1], but got %s", caps, v)
+		}
+	}
+
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"find(1, 'a')", "first argument for find() is expected list or string, but got int"},
+		{"find('abc', 1)", "second argument for find() is expected string, but got int"},
+		{"find([1], 'a')", "unable to call string"},
+		{"find('abc')", "too many / less arguments for find()"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapString))
+		err := s.Eval([]rune(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %v", tt.text, tt.msg, err)
+		}
+	}
+}
+
+func TestFunBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"map([1], fun(x) return x + 'a' end)", "right side value of add expression is not a number"},
+		{"map([1], fun(x, y) return x end)", "not enough or too much arguments"},
+		{"map([1], 1)", "unable to call int"},
+		{"map(1, floor)", "first argument for map() is expected list, but got int"},
+		{"filter([1], fun(x) return x end)", "function for filter() is expected to return bool, but got int"},
+		{"each([1], fun(x) return y end)", "variable named 'y' is not found"},
+		{"reduce([], fun(a, b) return a end)", "reduce() of empty list with no initial value"},
+		{"all([1], fun(x) return 'a' end)", "function for all() is expected to return bool, but got string"},
+		{"zip([1], 2)", "argument 2 for zip() is expected list, but got int"},
+		{"range(0, 1, 0)", "step for range() must not be zero"},
+		{"range(0.5)", "argument for range() is expected int, but got number"},
+		{"flat_map([1], fun(x) return x end)", "function for flat_map() is expected to return list, but got int"},
+		{"sort_by([1, 2], fun(x) return x == 1 end)", "unable to compare bool"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapMath))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
+
+func TestLargeIntArgs(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("ints of builtins are 32 bits")
+	}
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return len(range(9223372036854775807))", VInt(9223372036854775807)},
+		{"return len(range(-9223372036854775807, 9223372036854775807, 4611686018427387904))", VInt(4)},
+		{"return len(range(0, -9223372036854775807 - 1, -9223372036854775807 - 1))", VInt(1)},
+		{"for x in range(9223372036854775806, 9223372036854775807) return x end", VInt(9223372036854775806)},
+		{"return list(range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807))", listOf(VInt(-9223372036854775807-1), VInt(-1), VInt(9223372036854775806))},
+		{"return repeat('', 9223372036854775807)", VString("")},
+		{"return pad_left('a', 4294967297 - 4294967294)", VString("  a")},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapString))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if v := s.RetVals.Pop(); v.String() != tt.expected.String() {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+
+	errorTests := []struct {
+		text string
+		msg  string
+	}{
+		{"range(-9223372036854775807 - 1, 9223372036854775807)", "range() has too many numbers"},
+		{"repeat('ab', 9223372036854775807)", "result of repeat() is too large"},
+		{"pad_left('a', 9223372036854775807)", "result of pad_left() is too large"},
+		{"range(9223372036854775807.0 * 2)", "argument for range() is expected int, but got number"},
+		{"list(range(9223372036854775807))", "range of 9223372036854775807 numbers is too long for list()"},
+		{"insert([], 4294967296, 1)", "index 4294967296 out of range for insert()"},
+	}
+	for _, tt := range errorTests {
+		s := NewState(WithCapabilities(CapCore, CapString))
+		err := s.Eval([]rune(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %v", tt.text, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fj68/vvlang/value"
+)
+
+var listBuiltins = map[string]Value{
+	"push":     VBuiltinFun(builtinPush),
+	"pop":      VBuiltinFun(builtinPop),
+	"shift":    VBuiltinFun(builtinShift),
+	"unshift":  VBuiltinFun(builtinUnshift),
+	"insert":   VBuiltinFun(builtinInsert),
+	"remove":   VBuiltinFun(builtinRemove),
+	"reverse":  VBuiltinFun(builtinReverse),
+	"sort":     VBuiltinFun(builtinSort),
+	"index_of": VBuiltinFun(builtinIndexOf),
+	"contains": VBuiltinFun(builtinContains),
+	"concat":   VBuiltinFun(builtinConcat),
+}
+
+func listArg(name string, args []Value, i int) (*VList, error) {
+	list, ok := args[i].(*VList)
+	if !ok {
+		return nil, argError(name, args, i, "list")
+	}
+	return list, nil
+}
+
+// indexArg returns the index argument of the builtin name, counting negative indices from the end.
+// The index can be equal to length if allowEnd is true, e.g. to insert at the end.
+func indexArg(name string, args []Value, i int, length int, allowEnd bool) (int, error) {
+	index, err := intArg(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	idx := index
+	if idx < 0 {
+		idx = length + idx
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if idx < 0 || max < idx {
+		return 0, fmt.Errorf("index %d out of range for %s()", index, name)
+	}
+	return idx, nil
+}
+
+func builtinPush(s *State, args []Value) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("too many / less arguments for push()")
+	}
+	list, err := listArg("push", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(args) - 1); err != nil {
+		return nil, err
+	}
+	list.Elements = append(list.Elements, args[1:]...)
+	return nil, nil
+}
+
+func builtinPop(s *State, args []Value) (Value, error) {
+	if err := expectArgs("pop", args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg("pop", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Elements) == 0 {
+		return nil, fmt.Errorf("pop() from empty list")
+	}
+	last := list.Elements[len(list.Elements)-1]
+	list.Elements = list.Elements[:len(list.Elements)-1]
+	return last, nil
+}
+
+func builtinShift(s *State, args []Value) (Value, error) {
+	if err := expectArgs("shift", args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg("shift", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Elements) == 0 {
+		return nil, fmt.Errorf("shift() from empty list")
+	}
+	first := list.Elements[0]
+	list.Elements = append(list.Elements[:0], list.Elements[1:]...)
+	return first, nil
+}
+
+func builtinUnshift(s *State, args []Value) (Value, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("too many / less arguments for unshift()")
+	}
+	list, err := listArg("unshift", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(args) - 1); err != nil {
+		return nil, err
+	}
+	elements := make([]Value, 0, len(args)-1+len(list.Elements))
+	elements = append(elements, args[1:]...)
+	list.Elements = append(elements, list.Elements...)
+	return nil, nil
+}
+
+func builtinInsert(s *State, args []Value) (Value, error) {
+	if err := expectArgs("insert", args, 3, 3); err != nil {
+		return nil, err
+	}
+	list, err := listArg("insert", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := indexArg("insert", args, 1, len(list.Elements), true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(1); err != nil {
+		return nil, err
+	}
+	list.Elements = append(list.Elements, nil)
+	copy(list.Elements[idx+1:], list.Elements[idx:])
+	list.Elements[idx] = args[2]
+	return nil, nil
+}
+
+func builtinRemove(s *State, args []Value) (Value, error) {
+	if err := expectArgs("remove", args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg("remove", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := indexArg("remove", args, 1, len(list.Elements), false)
+	if err != nil {
+		return nil, err
+	}
+	removed := list.Elements[idx]
+	list.Elements = append(list.Elements[:idx], list.Elements[idx+1:]...)
+	return removed, nil
+}
+
+func builtinReverse(s *State, args []Value) (Value, error) {
+	if err := expectArgs("reverse", args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg("reverse", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(list.Elements)-1; i < j; i, j = i+1, j-1 {
+		list.Elements[i], list.Elements[j] = list.Elements[j], list.Elements[i]
+	}
+	return nil, nil
+}
+
+func builtinSort(s *State, args []Value) (Value, error) {
+	if err := expectArgs("sort", args, 1, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg("sort", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	less := func(a, b Value) (bool, error) {
+		return a.LessThan(b)
+	}
+	if len(args) == 2 {
+		less = func(a, b Value) (bool, error) {
+			return callComparator(s, "sort", args[1], a, b)
+		}
+	}
+	return nil, sortValues(list.Elements, less)
+}
+
+// callComparator calls the comparator function cmp of the builtin name, which should return bool.
+func callComparator(s *State, name string, cmp Value, a Value, b Value) (bool, error) {
+	v, err := s.callValue(cmp, []Value{a, b})
+	if err != nil {
+		return false, err
+	}
+	lt, ok := v.(VBool)
+	if !ok {
+		return false, fmt.Errorf("comparator for %s() is expected to return bool, but got %s", name, typeOf(v))
+	}
+	return bool(lt), nil
+}
+
+// sortValues sorts values stably by less, stopping at the first error.
+func sortValues(values []Value, less func(a, b Value) (bool, error)) error {
+	var err error
+	sort.SliceStable(values, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		var lt bool
+		lt, err = less(values[i], values[j])
+		return lt
+	})
+	return err
+}
+
+// typeOf returns the type name of v, which may be Go nil.
+func typeOf(v Value) string {
+	if v == nil {
+		return "nil"
+	}
+	return v.Type().String()
+}
+
+func builtinIndexOf(s *State, args []Value) (Value, error) {
+	if err := expectArgs("index_of", args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg("index_of", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	i, err := indexOfValue(list, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return VInt(i), nil
+}
+
+func builtinContains(s *State, args []Value) (Value, error) {
+	if err := expectArgs("contains", args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg("contains", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	i, err := indexOfValue(list, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return VBool(0 <= i), nil
+}
+
+// indexOfValue returns the index of the first element equal to v, or -1.
+func indexOfValue(list *VList, v Value) (int, error) {
+	for i, elem := range list.Elements {
+		eq, err := value.Equal(elem, v)
+		if err != nil {
+			return 0, err
+		}
+		if eq {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+func builtinConcat(s *State, args []Value) (Value, error) {
+	var elements []Value
+	for i := range args {
+		list, ok := args[i].(*VList)
+		if !ok {
+			return nil, fmt.Errorf("argument %d for concat() is expected list, but got %s", i+1, args[i].Type())
+		}
+		elements = append(elements, list.Elements...)
+	}
+	list := &VList{Elements: elements}
+	if err := s.allocValue(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
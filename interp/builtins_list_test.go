@@ -0,0 +1,89 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func ints(xs ...int64) *VList {
+	elements := make([]Value, len(xs))
+	for i, x := range xs {
+		elements[i] = VInt(x)
+	}
+	return &VList{Elements: elements}
+}
+
+func TestListBuiltins(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"xs = [1] push(xs, 2, 3) return xs", ints(1, 2, 3)},
+		{"xs = [1, 2] return [pop(xs), xs]", &VList{Elements: []Value{VInt(2), ints(1)}}},
+		{"xs = [1, 2] return [shift(xs), xs]", &VList{Elements: []Value{VInt(1), ints(2)}}},
+		{"xs = [3] unshift(xs, 1, 2) return xs", ints(1, 2, 3)},
+		{"xs = [1, 3] insert(xs, 1, 2) return xs", ints(1, 2, 3)},
+		{"xs = [1, 2] insert(xs, 2, 3) return xs", ints(1, 2, 3)},
+		{"xs = [2, 3] insert(xs, -2, 1) return xs", ints(1, 2, 3)},
+		{"xs = [1, 9, 2] return [remove(xs, 1), xs]", &VList{Elements: []Value{VInt(9), ints(1, 2)}}},
+		{"xs = [1, 2, 9] remove(xs, -1) return xs", ints(1, 2)},
+		{"xs = [1, 2, 3] reverse(xs) return xs", ints(3, 2, 1)},
+		{"xs = [] reverse(xs) return xs", ints()},
+		{"xs = [3, 1, 2] sort(xs) return xs", ints(1, 2, 3)},
+		{"xs = ['b', 'c', 'a'] sort(xs) return xs", strs("a", "b", "c")},
+		{"xs = [3, 1, 2] sort(xs, fun(a, b) return b < a end) return xs", ints(3, 2, 1)},
+		{"xs = [[2, 'a'], [1, 'b'], [2, 'c']] sort(xs, fun(a, b) return a[0] < b[0] end) return xs", &VList{Elements: []Value{
+			&VList{Elements: []Value{VInt(1), VString("b")}},
+			&VList{Elements: []Value{VInt(2), VString("a")}},
+			&VList{Elements: []Value{VInt(2), VString("c")}},
+		}}},
+		{"return index_of([1, 'a', [2]], [2])", VInt(2)},
+		{"return index_of([1, 2], 3)", VInt(-1)},
+		{"return contains([1, 'a'], 'a')", VBool(true)},
+		{"return contains([1, 'a'], 'b')", VBool(false)},
+		{"return concat([1], [], [2, 3])", ints(1, 2, 3)},
+		{"xs = [1] ys = concat(xs) push(ys, 2) return xs", ints(1)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestListBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"push([])", "too many / less arguments for push()"},
+		{"push('a', 1)", "first argument for push() is expected list, but got string"},
+		{"pop([])", "pop() from empty list"},
+		{"shift([])", "shift() from empty list"},
+		{"insert([1], 2, 0)", "index 2 out of range for insert()"},
+		{"insert([1], 0.5, 0)", "second argument for insert() is expected int, but got number"},
+		{"remove([1], 1)", "index 1 out of range for remove()"},
+		{"remove([1], -2)", "index -2 out of range for remove()"},
+		{"remove([], 0)", "index 0 out of range for remove()"},
+		{"sort([1, 'a'])", "expected string, but got int"},
+		{"sort([1, 2], fun(a, b) return 1 end)", "comparator for sort() is expected to return bool, but got int"},
+		{"sort([1, 2], fun(a, b) return a < 'x' end)", "expected number, but got string"},
+		{"sort([1, 2], 3)", "unable to call int"},
+		{"concat([1], 'a')", "argument 2 for concat() is expected list, but got string"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
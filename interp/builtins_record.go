@@ -0,0 +1,147 @@
+package interp
+
+import "fmt"
+
+var recordBuiltins = map[string]Value{
+	"keys":    VBuiltinFun(builtinKeys),
+	"values":  VBuiltinFun(builtinValues),
+	"entries": VBuiltinFun(builtinEntries),
+	"has":     VBuiltinFun(builtinHas),
+	"get":     VBuiltinFun(builtinGet),
+	"set":     VBuiltinFun(builtinSet),
+	"delete":  VBuiltinFun(builtinDelete),
+	"merge":   VBuiltinFun(builtinMerge),
+}
+
+func recordArg(name string, args []Value, i int) (*VRecord, error) {
+	rec, ok := args[i].(*VRecord)
+	if !ok {
+		return nil, argError(name, args, i, "record")
+	}
+	return rec, nil
+}
+
+// recordKeyArgs returns the record and the key arguments of the builtin name, e.g. has(r, key).
+func recordKeyArgs(name string, args []Value, min int, max int) (*VRecord, string, error) {
+	if err := expectArgs(name, args, min, max); err != nil {
+		return nil, "", err
+	}
+	rec, err := recordArg(name, args, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := stringArg(name, args, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	return rec, key, nil
+}
+
+// mapRecord makes a list of f(key, value) for each field of the only record argument of the builtin name.
+// size is the size of a value built by f, counted by alloc for each field.
+func mapRecord(s *State, name string, args []Value, size int, f func(key string, value Value) Value) (Value, error) {
+	if err := expectArgs(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	rec, err := recordArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(rec.Fields) * size); err != nil {
+		return nil, err
+	}
+	keys := rec.Keys()
+	elements := make([]Value, len(keys))
+	for i, k := range keys {
+		elements[i] = f(k, rec.Fields[k])
+	}
+	return s.newList(elements)
+}
+
+func builtinKeys(s *State, args []Value) (Value, error) {
+	return mapRecord(s, "keys", args, 0, func(key string, value Value) Value {
+		return VString(key)
+	})
+}
+
+func builtinValues(s *State, args []Value) (Value, error) {
+	return mapRecord(s, "values", args, 0, func(key string, value Value) Value {
+		return value
+	})
+}
+
+func builtinEntries(s *State, args []Value) (Value, error) {
+	return mapRecord(s, "entries", args, 3, func(key string, value Value) Value {
+		return &VList{Elements: []Value{VString(key), value}}
+	})
+}
+
+func builtinHas(s *State, args []Value) (Value, error) {
+	rec, key, err := recordKeyArgs("has", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := rec.Fields[key]
+	return VBool(ok), nil
+}
+
+// builtinGet returns the field of the key, or the default value, which is nil if omitted.
+func builtinGet(s *State, args []Value) (Value, error) {
+	rec, key, err := recordKeyArgs("get", args, 2, 3)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := rec.Fields[key]; ok {
+		return v, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, nil
+}
+
+func builtinSet(s *State, args []Value) (Value, error) {
+	rec, key, err := recordKeyArgs("set", args, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rec.Fields[key]; !ok {
+		if err := s.alloc(1); err != nil {
+			return nil, err
+		}
+	}
+	rec.Fields[key] = args[2]
+	return nil, nil
+}
+
+// builtinDelete removes the field of the key and returns its value, or nil if not found.
+func builtinDelete(s *State, args []Value) (Value, error) {
+	rec, key, err := recordKeyArgs("delete", args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	v := rec.Fields[key]
+	delete(rec.Fields, key)
+	return v, nil
+}
+
+// builtinMerge makes a new record of fields of all arguments, where later ones win.
+func builtinMerge(s *State, args []Value) (Value, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("too many / less arguments for merge()")
+	}
+	merged := &VRecord{Fields: map[string]Value{}}
+	for i := range args {
+		rec, ok := args[i].(*VRecord)
+		if !ok {
+			return nil, fmt.Errorf("argument %d for merge() is expected record, but got %s", i+1, typeOf(args[i]))
+		}
+		for k, v := range rec.Fields {
+			merged.Fields[k] = v
+		}
+	}
+	if err := s.allocValue(merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
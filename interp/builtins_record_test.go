@@ -0,0 +1,77 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordBuiltins(t *testing.T) {
+	rec := func(kvs ...Value) *VRecord {
+		fields := map[string]Value{}
+		for i := 0; i < len(kvs); i += 2 {
+			fields[string(kvs[i].(VString))] = kvs[i+1]
+		}
+		return &VRecord{Fields: fields}
+	}
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return keys({ b = 1, a = 2 })", strs("a", "b")},
+		{"return values({ b = 1, a = 2 })", ints(2, 1)},
+		{"return keys({})", strs()},
+		{"return entries({ b = 1, a = 2 })", &VList{Elements: []Value{
+			&VList{Elements: []Value{VString("a"), VInt(2)}},
+			&VList{Elements: []Value{VString("b"), VInt(1)}},
+		}}},
+		{"return has({ a = 1 }, 'a')", VBool(true)},
+		{"return has({ a = 1 }, 'b')", VBool(false)},
+		{"return get({ a = 1 }, 'a', 0)", VInt(1)},
+		{"return get({ a = 1 }, 'b', 0)", VInt(0)},
+		{"r = { a = 1 } set(r, 'b c', 2) return r", rec(VString("a"), VInt(1), VString("b c"), VInt(2))},
+		{"r = { a = 1, b = 2 } return [delete(r, 'a'), r]", &VList{Elements: []Value{VInt(1), rec(VString("b"), VInt(2))}}},
+		{"r = { a = 1 } delete(r, 'x') return r", rec(VString("a"), VInt(1))},
+		{"a = { x = 1, y = 2 } b = merge(a, { y = 3 }) return [a, b]", &VList{Elements: []Value{
+			rec(VString("x"), VInt(1), VString("y"), VInt(2)),
+			rec(VString("x"), VInt(1), VString("y"), VInt(3)),
+		}}},
+		{"r = { a = 1 } k = 'a' return r[k]", VInt(1)},
+		{"r = {} r['x y'] = 1 r['x y'] = r['x y'] + 1 return r", rec(VString("x y"), VInt(2))},
+		{"counts = {} each(split('a b a', ' '), fun(w) counts[w] = get(counts, w, 0) + 1 end) return counts", rec(VString("a"), VInt(2), VString("b"), VInt(1))},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapString))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestRecordBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"keys([])", "argument for keys() is expected record, but got list"},
+		{"has({}, 1)", "second argument for has() is expected string, but got int"},
+		{"get({}, 'a', 1, 2)", "too many / less arguments for get()"},
+		{"merge({}, [])", "argument 2 for merge() is expected record, but got list"},
+		{"r = {} return r['a']", "record does not have field 'a'"},
+		{"r = {} return r[1]", "record key must be a string, got int"},
+		{"r = {} r[1] = 2", "record key must be a string, got int"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var stringBuiltins = map[string]Value{
+	"split":       VBuiltinFun(builtinSplit),
+	"join":        VBuiltinFun(builtinJoin),
+	"trim":        VBuiltinFun(builtinTrim),
+	"trim_start":  VBuiltinFun(builtinTrimStart),
+	"trim_end":    VBuiltinFun(builtinTrimEnd),
+	"upper":       VBuiltinFun(builtinUpper),
+	"lower":       VBuiltinFun(builtinLower),
+	"find":        VBuiltinFun(builtinFind),
+	"replace":     VBuiltinFun(builtinReplace),
+	"starts_with": VBuiltinFun(builtinStartsWith),
+	"ends_with":   VBuiltinFun(builtinEndsWith),
+	"repeat":      VBuiltinFun(builtinRepeat),
+	"chars":       VBuiltinFun(builtinChars),
+	"ord":         VBuiltinFun(builtinOrd),
+	"chr":         VBuiltinFun(builtinChr),
+	"pad_left":    VBuiltinFun(builtinPadLeft),
+	"pad_right":   VBuiltinFun(builtinPadRight),
+}
+
+// runeArg returns the only rune of the string argument.
+func runeArg(name string, args []Value, i int) (rune, error) {
+	str, err := stringArg(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if utf8.RuneCountInString(str) != 1 {
+		return 0, fmt.Errorf("%s is expected a single character, but got '%s'", argLabel(name, args, i), str)
+	}
+	r, _ := utf8.DecodeRuneInString(str)
+	return r, nil
+}
+
+// repeatedSize returns the size of a string of n parts of size bytes,
+// which builtins count by alloc before building it, so that the limit stops huge strings.
+func repeatedSize(name string, n, size int) (int, error) {
+	if 0 < size && maxBuiltSize/size < n {
+		return 0, fmt.Errorf("result of %s() is too large", name)
+	}
+	return n * size, nil
+}
+
+func (s *State) newStringList(strs []string) (Value, error) {
+	elements := make([]Value, len(strs))
+	for i, str := range strs {
+		elements[i] = VString(str)
+	}
+	list := &VList{Elements: elements}
+	if err := s.allocValue(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func builtinSplit(s *State, args []Value) (Value, error) {
+	if err := expectArgs("split", args, 2, 2); err != nil {
+		return nil, err
+	}
+	text, err := stringArg("split", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg("split", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	// an empty separator splits into characters
+	return s.newStringList(strings.Split(text, sep))
+}
+
+func builtinJoin(s *State, args []Value) (Value, error) {
+	if err := expectArgs("join", args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, ok := args[0].(*VList)
+	if !ok {
+		return nil, argError("join", args, 0, "list")
+	}
+	sep, err := stringArg("join", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(list.Elements))
+	size := 0
+	for i, elem := range list.Elements {
+		str, ok := elem.(VString)
+		if !ok {
+			return nil, fmt.Errorf("element %d for join() is expected string, but got %s", i, elem.Type())
+		}
+		strs[i] = string(str)
+		size += len(str)
+	}
+	if 1 < len(strs) {
+		seps, err := repeatedSize("join", len(strs)-1, len(sep))
+		if err != nil {
+			return nil, err
+		}
+		size += seps
+	}
+	if err := s.alloc(size); err != nil {
+		return nil, err
+	}
+	return VString(strings.Join(strs, sep)), nil
+}
+
+func builtinTrim(s *State, args []Value) (Value, error) {
+	return mapString(s, "trim", args, strings.TrimSpace)
+}
+
+func builtinTrimStart(s *State, args []Value) (Value, error) {
+	return mapString(s, "trim_start", args, func(str string) string {
+		return strings.TrimLeftFunc(str, unicode.IsSpace)
+	})
+}
+
+func builtinTrimEnd(s *State, args []Value) (Value, error) {
+	return mapString(s, "trim_end", args, func(str string) string {
+		return strings.TrimRightFunc(str, unicode.IsSpace)
+	})
+}
+
+func builtinUpper(s *State, args []Value) (Value, error) {
+	return mapString(s, "upper", args, strings.ToUpper)
+}
+
+func builtinLower(s *State, args []Value) (Value, error) {
+	return mapString(s, "lower", args, strings.ToLower)
+}
+
+// mapString applies f to the only string argument of the builtin name.
+// The result is counted as large as the argument, and more if it grows, e.g. by upper().
+func mapString(s *State, name string, args []Value, f func(string) string) (Value, error) {
+	if err := expectArgs(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	str, err := stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(str)); err != nil {
+		return nil, err
+	}
+	result := f(str)
+	if len(str) < len(result) {
+		if err := s.alloc(len(result) - len(str)); err != nil {
+			return nil, err
+		}
+	}
+	return VString(result), nil
+}
+
+// stringArgs2 returns the two string arguments of the builtin name.
+func stringArgs2(name string, args []Value) (string, string, error) {
+	if err := expectArgs(name, args, 2, 2); err != nil {
+		return "", "", err
+	}
+	a, err := stringArg(name, args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := stringArg(name, args, 1)
+	if err != nil {
+		return "", "", err
+	}
+	return a, b, nil
+}
+
+// findSubstring returns the index of sub in text for find(text, sub), or -1 if not found.
+func findSubstring(args []Value) (Value, error) {
+	text, sub, err := stringArgs2("find", args)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.Index(text, sub)
+	if i < 0 {
+		return VInt(-1), nil
+	}
+	// index in characters, not in bytes
+	return VInt(utf8.RuneCountInString(text[:i])), nil
+}
+
+func builtinReplace(s *State, args []Value) (Value, error) {
+	if err := expectArgs("replace", args, 3, 3); err != nil {
+		return nil, err
+	}
+	text, err := stringArg("replace", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	old, err := stringArg("replace", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	new, err := stringArg("replace", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	size := len(text)
+	if len(old) < len(new) {
+		// an empty old matches around every character
+		grown, err := repeatedSize("replace", strings.Count(text, old), len(new)-len(old))
+		if err != nil {
+			return nil, err
+		}
+		size += grown
+	}
+	if err := s.alloc(size); err != nil {
+		return nil, err
+	}
+	return VString(strings.ReplaceAll(text, old, new)), nil
+}
+
+func builtinStartsWith(s *State, args []Value) (Value, error) {
+	text, prefix, err := stringArgs2("starts_with", args)
+	if err != nil {
+		return nil, err
+	}
+	return VBool(strings.HasPrefix(text, prefix)), nil
+}
+
+func builtinEndsWith(s *State, args []Value) (Value, error) {
+	text, suffix, err := stringArgs2("ends_with", args)
+	if err != nil {
+		return nil, err
+	}
+	return VBool(strings.HasSuffix(text, suffix)), nil
+}
+
+func builtinRepeat(s *State, args []Value) (Value, error) {
+	if err := expectArgs("repeat", args, 2, 2); err != nil {
+		return nil, err
+	}
+	text, err := stringArg("repeat", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg("repeat", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("count for repeat() must not be negative, but got %d", n)
+	}
+	size, err := repeatedSize("repeat", n, len(text))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(size); err != nil {
+		return nil, err
+	}
+	return VString(strings.Repeat(text, n)), nil
+}
+
+func builtinChars(s *State, args []Value) (Value, error) {
+	if err := expectArgs("chars", args, 1, 1); err != nil {
+		return nil, err
+	}
+	text, err := stringArg("chars", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.newStringList(strings.Split(text, ""))
+}
+
+func builtinOrd(s *State, args []Value) (Value, error) {
+	if err := expectArgs("ord", args, 1, 1); err != nil {
+		return nil, err
+	}
+	r, err := runeArg("ord", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return VInt(r), nil
+}
+
+func builtinChr(s *State, args []Value) (Value, error) {
+	if err := expectArgs("chr", args, 1, 1); err != nil {
+		return nil, err
+	}
+	n, err := intArg("chr", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || !utf8.ValidRune(rune(n)) {
+		return nil, fmt.Errorf("invalid character code %d for chr()", n)
+	}
+	return VString(string(rune(n))), nil
+}
+
+func builtinPadLeft(s *State, args []Value) (Value, error) {
+	return pad(s, "pad_left", args, func(text, padding string) string {
+		return padding + text
+	})
+}
+
+func builtinPadRight(s *State, args []Value) (Value, error) {
+	return pad(s, "pad_right", args, func(text, padding string) string {
+		return text + padding
+	})
+}
+
+// pad fills text up to width characters with the optional pad character, which defaults to a space.
+func pad(s *State, name string, args []Value, join func(text, padding string) string) (Value, error) {
+	if err := expectArgs(name, args, 2, 3); err != nil {
+		return nil, err
+	}
+	text, err := stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	width, err := intArg(name, args, 1)
+	if err != nil {
+		return nil, err
+	}
+	r := ' '
+	if len(args) == 3 {
+		r, err = runeArg(name, args, 2)
+		if err != nil {
+			return nil, err
+		}
+	}
+	n := width - utf8.RuneCountInString(text)
+	if n <= 0 {
+		return VString(text), nil
+	}
+	size, err := repeatedSize(name, n, utf8.RuneLen(r))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.alloc(len(text) + size); err != nil {
+		return nil, err
+	}
+	return VString(join(text, strings.Repeat(string(r), n))), nil
+}
@@ -0,0 +1,28 @@
+package interp
+
+import "testing"
+
+func TestArgLabel(t *testing.T) {
+	args := []Value{VInt(1), VInt(2), VInt(3), VInt(4), VInt(5)}
+	tests := []struct {
+		args     []Value
+		i        int
+		expected string
+	}{
+		{args[:1], 0, "argument for f()"},
+		{args, 0, "first argument for f()"},
+		{args, 2, "third argument for f()"},
+		// builtins may take more arguments than ordinals
+		{args, 3, "argument 4 for f()"},
+		{args, 4, "argument 5 for f()"},
+	}
+	for _, tt := range tests {
+		if actual := argLabel("f", tt.args, tt.i); actual != tt.expected {
+			t.Fatalf("%d of %d: expected %q, but got %q", tt.i, len(tt.args), tt.expected, actual)
+		}
+	}
+	expected := "argument 4 for f() is expected string, but got int"
+	if err := argError("f", args, 3, "string"); err.Error() != expected {
+		t.Fatalf("expected %q, but got %q", expected, err)
+	}
+}
@@ -0,0 +1,38 @@
+package interp
+
+import "fmt"
+
+// Global returns the value of the global variable name,
+// which is defined by the main program or registered by the host.
+func (s *State) Global(name string) (Value, error) {
+	return s.Env.Get(name)
+}
+
+// Call calls the function stored in the global variable name with args.
+func (s *State) Call(name string, args ...Value) (Value, error) {
+	f, err := s.Global(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.CallValue(f, args...)
+}
+
+// CallValue calls fn, which is either a user-defined or builtin function, with args.
+func (s *State) CallValue(fn Value, args ...Value) (Value, error) {
+	if s.optionErr != nil {
+		return nil, s.optionErr
+	}
+	return s.callValue(fn, args)
+}
+
+func (s *State) callValue(f Value, args []Value) (Value, error) {
+	return s.machine.Call(f, args)
+}
+
+// callNative is called by the VM to call values other than VUserFun.
+func (s *State) callNative(f Value, args []Value) (Value, error) {
+	if f, ok := f.(VBuiltinFun); ok {
+		return f(s, args)
+	}
+	return nil, fmt.Errorf("unable to call %s", f.Type())
+}
@@ -0,0 +1,118 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	s := NewState()
+	s.RegisterGlobals(DefaultBuiltins)
+	text := `frames = 0
+fun update(dt)
+  frames = frames + 1
+  return frames * dt
+end
+fun draw()
+end`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		v, err := s.Call("update", VNumber(0.5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != VNumber(float64(i)*0.5) {
+			t.Fatalf("expected %v, but got %v", float64(i)*0.5, v)
+		}
+	}
+	v, err := s.Call("draw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != (VNil{}) {
+		t.Fatalf("expected nil, but got %v", v)
+	}
+	frames, err := s.Global("frames")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames != VInt(3) {
+		t.Fatalf("expected 3, but got %v", frames)
+	}
+}
+
+func TestCallBuiltin(t *testing.T) {
+	s := NewState()
+	s.RegisterGlobals(DefaultBuiltins)
+	v, err := s.Call("string", VNumber(1.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != VString("1.5") {
+		t.Fatalf("expected \"1.5\", but got %v", v)
+	}
+}
+
+func TestCallValue(t *testing.T) {
+	s := NewState()
+	s.RegisterGlobals(DefaultBuiltins)
+	if err := s.Eval([]rune("fun make_adder(n) return fun(x) return x + n end end return make_adder(10)")); err != nil {
+		t.Fatal(err)
+	}
+	add := s.RetVals.Pop()
+	v, err := s.CallValue(add, VNumber(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != VNumber(15) {
+		t.Fatalf("expected 15, but got %v", v)
+	}
+
+	not, err := s.Global("not")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = s.CallValue(not, VBool(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != VBool(false) {
+		t.Fatalf("expected false, but got %v", v)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	s := NewState()
+	s.RegisterGlobals(DefaultBuiltins)
+	if err := s.Eval([]rune("x = 1\nfun f(a)\n  return a + 'b'\nend")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		args []Value
+		msg  string
+	}{
+		{"nothing", nil, "variable named 'nothing' is not found"},
+		{"x", nil, "unable to call int"},
+		{"f", nil, "not enough or too much arguments"},
+		{"f", []Value{VNumber(1)}, "3:10: right side value of add expression is not a number"},
+	}
+	for _, tt := range tests {
+		_, err := s.Call(tt.name, tt.args...)
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+		if !strings.HasPrefix(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.name, tt.msg, err.Error())
+		}
+	}
+
+	_, err := s.Call("f", VNumber(1))
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected RuntimeError, but got %T", err)
+	}
+}
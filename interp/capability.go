@@ -0,0 +1,95 @@
+package interp
+
+import "fmt"
+
+// Capability is a name of a set of builtins.
+// Scripts can use only builtins of capabilities the State is created with.
+type Capability string
+
+const (
+	// CapCore is conversions and inspection of values, errors, list and record manipulation, and higher-order functions.
+	CapCore Capability = "core"
+	// CapMath is mathematical functions.
+	CapMath Capability = "math"
+	// CapString is functions manipulating strings.
+	CapString Capability = "string"
+	// CapIO is printing, reading and writing files, and importing modules.
+	CapIO Capability = "io"
+	// CapOS is access to environment variables.
+	CapOS Capability = "os"
+	// CapTime is the clock and sleeping.
+	CapTime Capability = "time"
+)
+
+// AllCapabilities lists every capability, e.g. for the CLI running trusted scripts.
+var AllCapabilities = []Capability{CapCore, CapMath, CapString, CapIO, CapOS, CapTime}
+
+var coreBuiltins = map[string]Value{
+	"not":    VBuiltinFun(builtinNot),
+	"type":   VBuiltinFun(builtinType),
+	"bool":   VBuiltinFun(builtinBool),
+	"number": VBuiltinFun(builtinNumber),
+	"string": VBuiltinFun(builtinString),
+	"len":    VBuiltinFun(builtinLen),
+	"list":   VBuiltinFun(builtinList),
+	"error":  VBuiltinFun(builtinError),
+}
+
+var capabilities = map[Capability]map[string]Value{
+	CapCore: union(coreBuiltins, listBuiltins, funBuiltins, recordBuiltins),
+	CapMath: {
+		"ceil":  VBuiltinFun(builtinCeil),
+		"floor": VBuiltinFun(builtinFloor),
+	},
+	CapString: stringBuiltins,
+	CapIO: {
+		"print":      VBuiltinFun(builtinPrint),
+		"read_file":  VBuiltinFun(builtinReadFile),
+		"write_file": VBuiltinFun(builtinWriteFile),
+		"import":     VBuiltinFun(builtinImport),
+	},
+	CapOS: {
+		"env": VBuiltinFun(builtinEnv),
+	},
+	CapTime: {
+		"now":   VBuiltinFun(builtinNow),
+		"sleep": VBuiltinFun(builtinSleep),
+	},
+}
+
+func union(sets ...map[string]Value) map[string]Value {
+	builtins := map[string]Value{}
+	for _, set := range sets {
+		for name, value := range set {
+			builtins[name] = value
+		}
+	}
+	return builtins
+}
+
+// Builtins returns builtins of caps, or an error if any of them is unknown,
+// e.g. when capabilities are read from a configuration.
+func Builtins(caps ...Capability) (map[string]Value, error) {
+	var sets []map[string]Value
+	for _, c := range caps {
+		set, ok := capabilities[c]
+		if !ok {
+			return nil, fmt.Errorf("unknown capability '%s'", c)
+		}
+		sets = append(sets, set)
+	}
+	return union(sets...), nil
+}
+
+// WithCapabilities registers builtins of caps, and only of them, as globals.
+// If any of caps is unknown, the State reports it by every evaluation and call instead of running them.
+func WithCapabilities(caps ...Capability) Option {
+	return func(s *State) {
+		builtins, err := Builtins(caps...)
+		if err != nil {
+			s.invalidOption(err)
+			return
+		}
+		s.RegisterGlobals(builtins)
+	}
+}
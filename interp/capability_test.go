@@ -0,0 +1,99 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapabilitiesRestrictBuiltins(t *testing.T) {
+	s := NewState(WithCapabilities(CapCore))
+	if err := s.Eval([]rune("return len('abc')")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"print", "read_file", "write_file", "import", "env", "now", "sleep", "floor"} {
+		err := s.Eval([]rune(name + "()"))
+		if err == nil || !strings.Contains(err.Error(), "variable named '"+name+"' is not found") {
+			t.Fatalf("%s: expected not found error, but got %v", name, err)
+		}
+	}
+}
+
+func TestDefaultBuiltinsArePure(t *testing.T) {
+	if _, ok := DefaultBuiltins["print"]; !ok {
+		t.Fatal("DefaultBuiltins should contain print")
+	}
+	for _, c := range []Capability{CapIO, CapOS, CapTime} {
+		builtins, err := Builtins(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name := range builtins {
+			if _, ok := DefaultBuiltins[name]; ok && name != "print" {
+				t.Fatalf("DefaultBuiltins should not contain %s of %s", name, c)
+			}
+		}
+	}
+}
+
+func TestIOBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	s := NewState(WithCapabilities(CapIO))
+	s.RegisterGlobal("path", VString(path))
+	if err := s.Eval([]rune("write_file(path, 'hello') return read_file(path)")); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.RetVals.Pop(); v != VString("hello") {
+		t.Fatalf("expected \"hello\", but got %v", v)
+	}
+}
+
+func TestOSBuiltins(t *testing.T) {
+	t.Setenv("VV_TEST_ENV", "value")
+	s := NewState(WithCapabilities(CapOS))
+	if err := s.Eval([]rune("return env('VV_TEST_ENV')")); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.RetVals.Pop(); v != VString("value") {
+		t.Fatalf("expected \"value\", but got %v", v)
+	}
+}
+
+func TestTimeBuiltins(t *testing.T) {
+	s := NewState(WithCapabilities(CapCore, CapTime))
+	if err := s.Eval([]rune("start = now() sleep(0.01) return now() - start")); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.RetVals.Pop().(VNumber); !ok || v < 0.01 {
+		t.Fatalf("expected at least 0.01, but got %v", v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := s.EvalContext(ctx, []rune("sleep(60)"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, but got %v", err)
+	}
+}
+
+func TestUnknownCapability(t *testing.T) {
+	if _, err := Builtins(CapCore, "network"); err == nil || err.Error() != "unknown capability 'network'" {
+		t.Fatalf("expected unknown capability, but got %v", err)
+	}
+
+	// the State reports the invalid option instead of running without the builtins
+	s := NewState(WithCapabilities(CapCore, "network"))
+	expected := "invalid option: unknown capability 'network'"
+	if err := s.Eval([]rune("x = 1")); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, but got %v", expected, err)
+	}
+	if _, err := s.CallValue(DefaultBuiltins["len"], VString("a")); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, but got %v", expected, err)
+	}
+	if _, err := s.Import("missing"); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, but got %v", expected, err)
+	}
+}
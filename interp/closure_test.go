@@ -0,0 +1,181 @@
+package interp
+
+import "testing"
+
+func TestClosureReturnedFromFunction(t *testing.T) {
+	s := NewState()
+	text := `fun make_counter()
+  n = 0
+  return fun()
+    n = n + 1
+    return n
+  end
+end
+c = make_counter()
+c()
+c()
+return c()`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3, got %v", n)
+	}
+}
+
+func TestClosureCountersAreIndependent(t *testing.T) {
+	s := NewState()
+	text := `fun make_counter()
+  n = 0
+  return fun()
+    n = n + 1
+    return n
+  end
+end
+a = make_counter()
+b = make_counter()
+a()
+a()
+b()
+return [a(), b()]`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	list, ok := v.(*VList)
+	if !ok {
+		t.Fatalf("expected *VList, got %T", v)
+	}
+	if eq, _ := list.Equal(&VList{Elements: []Value{VNumber(3), VNumber(2)}}); !eq {
+		t.Fatalf("expected [3, 2], got %s", list)
+	}
+}
+
+func TestClosureSeesDefiningScopeNotCaller(t *testing.T) {
+	s := NewState()
+	text := `fun make_adder(x)
+  return fun(y)
+    return x + y
+  end
+end
+fun call_with_x(f)
+  x = 100
+  return f(1)
+end
+return call_with_x(make_adder(10))`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 11 {
+		t.Fatalf("expected 11, got %v", n)
+	}
+}
+
+func TestClosureInList(t *testing.T) {
+	s := NewState()
+	text := `fun make_adder(x)
+  return fun(y)
+    return x + y
+  end
+end
+adders = [make_adder(1), make_adder(2)]
+return adders[1](10)`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 12 {
+		t.Fatalf("expected 12, got %v", n)
+	}
+}
+
+func TestClosureInRecord(t *testing.T) {
+	s := NewState()
+	text := `fun make_account(balance)
+  return {
+    deposit = fun(v)
+      balance = balance + v
+      return balance
+    end,
+    balance = fun()
+      return balance
+    end,
+  }
+end
+acc = make_account(5)
+acc.deposit(10)
+return acc.balance()`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 15 {
+		t.Fatalf("expected 15, got %v", n)
+	}
+}
+
+func TestClosureRecursionThroughCapturedName(t *testing.T) {
+	s := NewState()
+	text := `fun make_loop()
+  fun loop(i)
+    if i < 5
+      return loop(i + 1)
+    end
+    return i
+  end
+  return loop
+end
+f = make_loop()
+return f(0)`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 5 {
+		t.Fatalf("expected 5, got %v", n)
+	}
+}
+
+func TestApplyWithGlobalFunction(t *testing.T) {
+	s := NewState()
+	text := `fun incr(x)
+  return x + 1
+end
+apply = fun(v, f)
+  return f(v)
+end
+return apply(5, incr)`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	n, ok := v.(VInt)
+	if !ok {
+		t.Fatalf("expected VInt, got %T", v)
+	}
+	if n != 6 {
+		t.Fatalf("expected 6, got %v", n)
+	}
+}
@@ -0,0 +1,23 @@
+package interp
+
+import "github.com/fj68/vvlang/vm"
+
+type (
+	// RuntimeError is an error raised while evaluating a program,
+	// located at the innermost node that failed.
+	RuntimeError = vm.RuntimeError
+	TraceFrame   = vm.TraceFrame
+	Traceback    = vm.Traceback
+)
+
+// Kinds of VError.
+const (
+	ErrorKindThrown  = vm.ErrorKindThrown
+	ErrorKindRuntime = vm.ErrorKindRuntime
+	ErrorKindSyntax  = vm.ErrorKindSyntax
+)
+
+var (
+	ErrIntegerOverflow = vm.ErrIntegerOverflow
+	ErrDivisionByZero  = vm.ErrDivisionByZero
+)
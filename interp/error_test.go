@@ -0,0 +1,129 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/lexer"
+)
+
+func TestRuntimeErrorPosition(t *testing.T) {
+	s := NewState()
+	text := `fun f(x)
+  return x + 'a'
+end
+y = 1
+f(y)`
+	err := s.EvalSource(lexer.NewSource("test.vv", []rune(text)))
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RuntimeError, got %T: %v", err, err)
+	}
+	expected := "test.vv:2:10: right side value of add expression is not a number\n  return x + 'a'\n         ^^^^^^^"
+	if rerr.Error() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, rerr.Error())
+	}
+}
+
+func TestRuntimeErrorUnwrap(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("x = 1\nreturn x / 0"))
+	if !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("expected division by zero, got %v", err)
+	}
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RuntimeError, got %T", err)
+	}
+	if line, col := rerr.Source.LineCol(rerr.Pos.Start); line != 2 || col != 8 {
+		t.Fatalf("expected 2:8, got %d:%d", line, col)
+	}
+}
+
+func TestRuntimeErrorCondition(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("x = 1\nif x\n  x = 2\nend"))
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RuntimeError, got %T", err)
+	}
+	if line, col := rerr.Source.LineCol(rerr.Pos.Start); line != 2 || col != 4 {
+		t.Fatalf("expected 2:4, got %d:%d", line, col)
+	}
+}
+
+// traceOf returns frames of the traceback of err as "name line:col".
+func traceOf(t *testing.T, err error) []string {
+	t.Helper()
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RuntimeError, got %T: %v", err, err)
+	}
+	var frames []string
+	for _, f := range rerr.Traceback {
+		line, col := f.Source.LineCol(f.Pos.Start)
+		frames = append(frames, fmt.Sprintf("%s %d:%d", f.Name, line, col))
+	}
+	return frames
+}
+
+func TestTraceback(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"x = 1\nreturn x / 0", []string{"<top level> 2:8"}},
+		{
+			"fun g(x)\n  return x + 'a'\nend\nfun f(x)\n  return g(x)\nend\nf(1)",
+			[]string{"g 2:10", "f 5:10", "<top level> 7:1"},
+		},
+		// builtins are not frames, but functions they call are
+		{
+			"fun f(x)\n  return map([x], fun(y) return y.z end)\nend\nf(1)",
+			[]string{"<anonymous> 2:33", "f 2:10", "<top level> 4:1"},
+		},
+		// errors thrown again keep the traceback where they are raised first
+		{
+			"fun g() throw 'x' end\nfun f()\n  try g() catch e throw e end\nend\nf()",
+			[]string{"g 1:9", "f 3:7", "<top level> 5:1"},
+		},
+		{
+			"fun g() throw 'x' end\ntry\n  g()\nfinally\nend",
+			[]string{"g 1:9", "<top level> 3:3"},
+		},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		frames := traceOf(t, s.Eval([]rune(tt.text)))
+		if strings.Join(frames, ", ") != strings.Join(tt.expected, ", ") {
+			t.Fatalf("%s: expected %v, got %v", tt.text, tt.expected, frames)
+		}
+	}
+}
+
+func TestTracebackCall(t *testing.T) {
+	s := NewState()
+	if err := s.Eval([]rune("fun f(x)\n  return x.y\nend")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.Call("f", VInt(1))
+	frames := traceOf(t, err)
+	if len(frames) != 1 || frames[0] != "f 2:10" {
+		t.Fatalf("expected [f 2:10], got %v", frames)
+	}
+}
+
+func TestTracebackString(t *testing.T) {
+	s := NewState()
+	err := s.EvalSource(lexer.NewSource("test.vv", []rune("fun f(n)\n  return f(n + 1)\nend\nf(0)")))
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RuntimeError, got %T: %v", err, err)
+	}
+	expected := fmt.Sprintf("traceback (most recent call first):\n  at f (test.vv:2:10)\n  ... repeated %d more times\n  at <top level> (test.vv:4:1)", DefaultMaxCallDepth-2)
+	if actual := rerr.Traceback.String(); actual != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,67 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"sum = 0 for x in [1, 2, 3] sum = sum + x end return sum", VInt(6)},
+		{"ys = [] for i, x in ['a', 'b'] push(ys, [i, x]) end return ys", &VList{Elements: []Value{
+			&VList{Elements: []Value{VInt(0), VString("a")}},
+			&VList{Elements: []Value{VInt(1), VString("b")}},
+		}}},
+		{"ys = [] for c in 'aあ😀' push(ys, c) end return ys", strs("a", "あ", "😀")},
+		{"ys = [] for i, c in 'あい' push(ys, i) end return ys", ints(0, 1)},
+		{"ys = [] for x in { b = 2, a = 1 } push(ys, x) end return ys", ints(1, 2)},
+		{"ys = [] for k, v in { b = 2, a = 1 } push(ys, k) end return ys", strs("a", "b")},
+		{"ys = [] for x in range(3) push(ys, x) end return ys", ints(0, 1, 2)},
+		{"ys = [] for i, x in range(10, 0, -5) push(ys, [i, x]) end return ys", &VList{Elements: []Value{ints(0, 10), ints(1, 5)}}},
+		{"ys = [] for x in [] push(ys, x) end return ys", ints()},
+		{"ys = [] for x in range(10) if x == 3 break end push(ys, x) end return ys", ints(0, 1, 2)},
+		{"ys = [] for x in range(5) if x mod 2 == 0 continue end push(ys, x) end return ys", ints(1, 3)},
+		{"ys = [] for x in [1, 2] for y in [3, 4] if y == 4 break end push(ys, x * y) end end return ys", ints(3, 6)},
+		{"ys = [] for x in [1, 2] i = 0 while true i = i + 1 if i == 2 break end end push(ys, x + i) end return ys", ints(3, 4)},
+		{"fun f(xs) for x in xs if 1 < x return x end end return 0 end return f([1, 2, 3])", VInt(2)},
+		{"fun f(xs) n = 0 for x in xs n = n + x end return n end return f(range(4))", VInt(6)},
+		{"fun f() fs = [] for x in [1, 2] push(fs, fun() return x end) end return fs end fs = f() return fs[0]()", VInt(2)},
+		{"xs = [1] for x in xs if x < 3 push(xs, x + 1) end end return xs", ints(1, 2, 3)},
+		{"r = { a = 1, b = 2 } ys = [] for k, v in r delete(r, 'b') push(ys, k) end return ys", strs("a")},
+		{"for x in [1, 2] end return x", VInt(2)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestForError(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"for x in 1 end", "unable to iterate int"},
+		{"for x in len end", "unable to iterate fun"},
+		{"for x in [1] x + 'a' end", "right side value of add expression is not a number"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package interp
+
+import "testing"
+
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected VString
+	}{
+		{`sprite = { name = 'player', face = 'left' } return "{sprite.name}_{sprite.face}.png"`, "player_left.png"},
+		{`x = 1.5 return "x = {x}, x * 2 = {x * 2}"`, "x = 1.5, x * 2 = 3"},
+		{`return "{true} {[1, 'a']} {{a = 1}}"`, `true [1, "a"] {a = 1}`},
+		{`name = 'vv' return "hello, {"<{name}>"}!"`, "hello, <vv>!"},
+		{`return "\{not interpolated}"`, "{not interpolated}"},
+		{`return "plain"`, "plain"},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		str, ok := v.(VString)
+		if !ok {
+			t.Fatalf("%s: expected VString, got %T", tt.text, v)
+		}
+		if str != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.text, tt.expected, str)
+		}
+	}
+}
+
+func TestInterpolatedStringError(t *testing.T) {
+	s := NewState()
+	if err := s.Eval([]rune(`return "{undefined_var}"`)); err == nil {
+		t.Fatal("expected error for undefined variable")
+	}
+}
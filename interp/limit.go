@@ -0,0 +1,64 @@
+package interp
+
+import (
+	"context"
+
+	"github.com/fj68/vvlang/vm"
+)
+
+// DefaultMaxCallDepth keeps unbounded recursion from exhausting the Go stack.
+const DefaultMaxCallDepth = 10000
+
+type Option func(*State)
+
+// WithMaxSteps limits the number of instructions executed by one evaluation.
+// 0 means unlimited.
+func WithMaxSteps(n int) Option {
+	return func(s *State) { s.machine.MaxSteps = n }
+}
+
+// WithMaxCallDepth limits the depth of nested function calls.
+// 0 means unlimited, which may crash the host on unbounded recursion.
+func WithMaxCallDepth(n int) Option {
+	return func(s *State) { s.machine.MaxCallDepth = n }
+}
+
+// WithMaxAllocs limits the size of lists, records and strings constructed by one evaluation,
+// counted as the number of elements, fields and bytes. 0 means unlimited.
+func WithMaxAllocs(n int) Option {
+	return func(s *State) { s.machine.MaxAllocs = n }
+}
+
+type (
+	LimitKind = vm.LimitKind
+	// LimitError is raised when an evaluation exceeds a limit of the State.
+	LimitError = vm.LimitError
+)
+
+const (
+	StepLimit      = vm.StepLimit
+	CallDepthLimit = vm.CallDepthLimit
+	AllocLimit     = vm.AllocLimit
+)
+
+// EvalContext evaluates text like Eval, but aborts with the error of ctx when it is done.
+func (s *State) EvalContext(ctx context.Context, text []rune) error {
+	prev := s.machine.Context
+	s.machine.Context = ctx
+	defer func() { s.machine.Context = prev }()
+	return s.Eval(text)
+}
+
+// maxBuiltSize is the number of bytes of a string, or elements of a list, which builtins build at most,
+// so that huge sizes are errors rather than panics of Go when the host does not limit allocations.
+const maxBuiltSize = 1 << 31
+
+// alloc counts n units of memory used to construct values.
+func (s *State) alloc(n int) error {
+	return s.machine.Alloc(n)
+}
+
+// allocValue counts memory of newly constructed v, not including its elements.
+func (s *State) allocValue(v Value) error {
+	return s.machine.AllocValue(v)
+}
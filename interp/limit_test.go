@@ -0,0 +1,126 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func expectLimitError(t *testing.T, err error, kind LimitKind) {
+	t.Helper()
+	var lerr *LimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected LimitError, but got %v", err)
+	}
+	if lerr.Kind != kind {
+		t.Fatalf("expected %s limit, but got %s limit", kind, lerr.Kind)
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	s := NewState(WithMaxSteps(1000))
+	err := s.Eval([]rune("while true end"))
+	expectLimitError(t, err, StepLimit)
+
+	// steps are counted per evaluation
+	for i := 0; i < 10; i++ {
+		if err := s.Eval([]rune("i = 0 while i < 10 i = i + 1 end")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("fun f() return f() end f()"))
+	expectLimitError(t, err, CallDepthLimit)
+
+	s = NewState(WithMaxCallDepth(50))
+	if err := s.Eval([]rune("fun down(n) if n == 0 return 0 end return down(n - 1) end return down(40)")); err != nil {
+		t.Fatal(err)
+	}
+	err = s.Eval([]rune("return down(60)"))
+	expectLimitError(t, err, CallDepthLimit)
+
+	// the state is still usable after hitting the limit
+	if err := s.Eval([]rune("return down(40)")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxAllocs(t *testing.T) {
+	tests := []string{
+		"xs = [] while true xs = [...xs, 1] end",
+		"r = {} while true r = { ...r, x = 1 } end",
+		"s = 'a' while true s = \"{s}{s}\" end",
+	}
+	for _, text := range tests {
+		s := NewState(WithMaxAllocs(1000))
+		err := s.Eval([]rune(text))
+		expectLimitError(t, err, AllocLimit)
+	}
+}
+
+func TestMaxAllocsOfStringBuiltins(t *testing.T) {
+	tests := []string{
+		"x = repeat('a', 2000)",
+		"x = pad_left('a', 2000)",
+		"s = repeat('a', 100) while true s = upper(s) end",
+		"s = repeat(' a ', 100) while true x = trim(s) end",
+		"s = 'ab' while true s = replace(s, 'a', 'aa') end",
+		"x = replace(repeat('a', 10), '', repeat('b', 100))",
+		"s = 'a' while true s = join([s, s], ',') end",
+	}
+	for _, text := range tests {
+		s := NewState(WithCapabilities(CapCore, CapString), WithMaxAllocs(1000))
+		err := s.Eval([]rune(text))
+		expectLimitError(t, err, AllocLimit)
+	}
+}
+
+func TestMaxAllocsOfNestedValues(t *testing.T) {
+	tests := []string{
+		// fields added by assignment are counted as set() does
+		"r = {} i = 0 while true r[string(i)] = i i = i + 1 end",
+		// lists in the result are counted as well as the result
+		"xs = list(range(300)) x = enumerate(xs)",
+		"xs = list(range(300)) x = zip(xs, xs)",
+		"r = {} for i in range(200) r[string(i)] = i end x = entries(r)",
+	}
+	for _, text := range tests {
+		s := NewState(WithCapabilities(CapCore), WithMaxAllocs(1000))
+		err := s.Eval([]rune(text))
+		expectLimitError(t, err, AllocLimit)
+	}
+
+	// assigning to existing fields does not grow records
+	s := NewState(WithMaxAllocs(3))
+	if err := s.Eval([]rune("r = {} r.a = 1 r.a = 2 r['a'] = 3 r.b = 4")); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Eval([]rune("r = {} r.a = 1 r.b = 2 r.c = 3"))
+	expectLimitError(t, err, AllocLimit)
+}
+
+func TestEvalContext(t *testing.T) {
+	s := NewState()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.EvalContext(ctx, []rune("x = 1"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, but got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = s.EvalContext(ctx, []rune("while true end"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, but got %v", err)
+	}
+
+	// Eval does not keep the context
+	if err := s.Eval([]rune("x = 1")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -17,11 +17,11 @@ func TestListLiteralEval(t *testing.T) {
 		t.Fatalf("expected 3 elements, got %d", len(list.Elements))
 	}
 	for i := 0; i < 3; i++ {
-		elem, ok := list.Elements[i].(VNumber)
+		elem, ok := list.Elements[i].(VInt)
 		if !ok {
-			t.Fatalf("expected VNumber for element %d, got %T", i, list.Elements[i])
+			t.Fatalf("expected VInt for element %d, got %T", i, list.Elements[i])
 		}
-		if elem != VNumber(float64(i)) {
+		if elem != VInt(i) {
 			t.Fatalf("expected %d, got %v", i, elem)
 		}
 	}
@@ -75,11 +75,11 @@ func TestListLiteralMixed(t *testing.T) {
 	}
 
 	// Check first element is a number
-	num, ok := list.Elements[0].(VNumber)
+	num, ok := list.Elements[0].(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber for element 0, got %T", list.Elements[0])
+		t.Fatalf("expected VInt for element 0, got %T", list.Elements[0])
 	}
-	if num != VNumber(42) {
+	if num != VInt(42) {
 		t.Fatalf("expected 42, got %v", num)
 	}
 
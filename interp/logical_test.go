@@ -0,0 +1,96 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected VBool
+	}{
+		{"return true and true", true},
+		{"return true and false", false},
+		{"return false or true", true},
+		{"return false or false", false},
+		{"return 1 < 2 and 2 < 3", true},
+		{"return 1 == 2 or 2 == 2 and 3 < 1", false},
+		{"return not true", false},
+		{"return not 1 == 2", true},
+		{"return not (true and false)", true},
+		{"return not true or true", true},
+		{"return 1 != 2", true},
+		{"return 'a' != 'a'", false},
+		{"return [1] != [1, 2]", true},
+		{"return 2 > 1", true},
+		{"return 1 > 1", false},
+		{"return 1 >= 1", true},
+		{"return 'b' >= 'a'", true},
+		{"return 'a' >= 'b'", false},
+		{"return (1 + 2) * 3 == 9", true},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		b, ok := v.(VBool)
+		if !ok {
+			t.Fatalf("%s: expected VBool, got %T", tt.text, v)
+		}
+		if b != tt.expected {
+			t.Fatalf("%s: expected %v, got %v", tt.text, tt.expected, b)
+		}
+	}
+}
+
+func TestLogicalShortCircuit(t *testing.T) {
+	s := NewState()
+	s.RegisterGlobals(DefaultBuiltins)
+	text := `xs = []
+i = 0
+a = i < len(xs) and xs[i] == 0
+b = len(xs) == 0 or xs[i] == 0
+return [a, b]`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	list, ok := v.(*VList)
+	if !ok {
+		t.Fatalf("expected *VList, got %T", v)
+	}
+	if eq, _ := list.Equal(&VList{Elements: []Value{VBool(false), VBool(true)}}); !eq {
+		t.Fatalf("expected [false, true], got %s", list)
+	}
+}
+
+func TestLogicalNonBoolOperand(t *testing.T) {
+	for _, text := range []string{"return 1 and true", "return false or 'a'"} {
+		s := NewState()
+		if err := s.Eval([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error for non-bool operand", text)
+		}
+	}
+}
+
+func TestComparisonErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"return not 1", "operand of not is expected bool, but got int"},
+		{"return 1 > 'a'", "expected string, but got int"},
+		{"return [1] >= [2]", "unable to compare lists"},
+		{"return 1 != 'a'", "expected number, but got string"},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		err := s.Eval([]rune(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %v", tt.text, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestElif(t *testing.T) {
+	tests := []struct {
+		x        float64
+		expected string
+	}{
+		{1, "one"},
+		{2, "two"},
+		{3, "three"},
+		{4, "many"},
+	}
+	for _, text := range []string{
+		"if x == 1 return 'one' elif x == 2 return 'two' elif x == 3 return 'three' else return 'many' end",
+		"if x == 1 return 'one' else if x == 2 return 'two' else if x == 3 return 'three' else return 'many' end",
+	} {
+		for _, tt := range tests {
+			s := NewState()
+			s.RegisterGlobal("x", VNumber(tt.x))
+			if err := s.Eval([]rune(text)); err != nil {
+				t.Fatalf("%s: %s", text, err)
+			}
+			if v := s.RetVals.Pop(); v != VString(tt.expected) {
+				t.Fatalf("%s: expected '%s' for %g, but got %s", text, tt.expected, tt.x, v)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	describe := `fun describe(x)
+  match x
+  case 0
+    return 'zero'
+  case 1, 2, 3
+    return 'small'
+  case -1
+    return 'minus one'
+  case 'a', true
+    return 'a or true'
+  case []
+    return 'empty'
+  case [a]
+    return "one {a}"
+  case [a, [b, _]]
+    return "nested {a} {b}"
+  case [a, ...rest]
+    return "list {a} {rest}"
+  case { kind = 'point', x = px, y = py }
+    return "point {px} {py}"
+  case { name = n, ...others }
+    return "named {n} {others}"
+  case {}
+    return 'record'
+  else
+    return 'other'
+  end
+end
+`
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"0", "zero"},
+		{"2", "small"},
+		{"-1", "minus one"},
+		{"'a'", "a or true"},
+		{"true", "a or true"},
+		{"false", "other"},
+		{"'b'", "other"},
+		{"[]", "empty"},
+		{"[1]", "one 1"},
+		{"[1, [2, 3]]", "nested 1 2"},
+		{"[1, [2]]", "list 1 [[2]]"},
+		{"[1, 2, 3]", "list 1 [2, 3]"},
+		{"{ kind = 'point', x = 1, y = 2, z = 3 }", "point 1 2"},
+		{"{ kind = 'line', name = 'l', len = 3 }", `named l {kind = "line", len = 3}`},
+		{"{ kind = 'point', x = 1 }", "record"},
+		{"describe", "other"},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		text := describe + "return describe(" + tt.text + ")"
+		if err := s.Eval([]rune(text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if v := s.RetVals.Pop(); v != VString(tt.expected) {
+			t.Fatalf("%s: expected '%s', but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestMatchStatement(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		// no case matches without else
+		{"y = 0 match 5 case 1 y = 1 end return y", VNumber(0)},
+		// bindings are variables of the scope
+		{"match [1, 2] case [a, b] end return a + b", VNumber(3)},
+		{"fun f(p) match p case [a, b] return fun() return a * b end end end return f([2, 3])()", VNumber(6)},
+		// break and continue in cases
+		{"n = 0 for x in [1, 2, 3, 4] match x case 2 continue case 4 break else n = n + x end end return n", VNumber(4)},
+		{"n = 0 i = 0 while i < 100 i = i + 1 match i case 3 break end n = n + 1 end return n", VNumber(2)},
+		// rest is a copy
+		{"xs = [1, 2] match xs case [_, ...r] r[0] = 9 end return xs[1]", VNumber(2)},
+		// variables in scope are compared instead of bound
+		{"k = 5 y = 0 match 7 case k y = k end return y", VInt(0)},
+		{"k = 7 match 7 case k return 'k' end return 'none'", VString("k")},
+		{"k = 1 match [2, 3] case [k, j] return j else return 'none' end", VString("none")},
+		{"k = 1 match [1, 3] case [k, j] return j else return 'none' end", VInt(3)},
+		{"fun f(x, k) match x case k return 'same' else return 'other' end end return [f(1, 1), f(1, 2)]", &VList{Elements: []Value{VString("same"), VString("other")}}},
+		{"fun f(k) return fun(x) match x case k return true end return false end end return f(3)(3)", VBool(true)},
+		// other expressions are compared too
+		{"p = { q = 2 } match 2 case p.q return 'q' end return 'none'", VString("q")},
+		{"p = { q = 2 } match 3 case p.q + 1, 9 return 'q + 1' end return 'none'", VString("q + 1")},
+		// names assigned by an earlier case are new to later ones
+		{"match 2 case [a] return 0 case a return a end", VInt(2)},
+		{"match 2 case 1 z = 1 case z return z end", VInt(2)},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestMatchError(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("match 1 case [...r, a] end"))
+	if err == nil || !strings.Contains(err.Error(), "rest of list pattern must be the last") {
+		t.Fatalf("expected rest of list pattern must be the last, but got %v", err)
+	}
+}
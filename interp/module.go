@@ -0,0 +1,117 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/parser"
+)
+
+// module is a loaded source file.
+type module struct {
+	// exports is nil while the module is being loaded.
+	exports *VRecord
+}
+
+func builtinImport(s *State, args []Value) (Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("too many / less arguments for import()")
+	}
+	name, ok := args[0].(VString)
+	if !ok {
+		return nil, fmt.Errorf("argument for import() is expected string, but got %s", args[0].Type())
+	}
+	return s.Import(string(name))
+}
+
+// Import loads the module of name and returns its public bindings.
+// A module is evaluated only once; later imports return the same record.
+func (s *State) Import(name string) (*VRecord, error) {
+	if s.optionErr != nil {
+		return nil, s.optionErr
+	}
+	path, err := s.resolveModule(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := s.modules[path]; ok {
+		if m.exports == nil {
+			return nil, fmt.Errorf("import cycle: %s -> %s", strings.Join(s.importing, " -> "), path)
+		}
+		return m.exports, nil
+	}
+
+	m := &module{}
+	s.modules[path] = m
+	s.importing = append(s.importing, path)
+	defer func() { s.importing = s.importing[:len(s.importing)-1] }()
+
+	env, err := s.loadModule(path)
+	if err != nil {
+		// allow importing again after fixing the module, e.g. in REPL
+		delete(s.modules, path)
+		return nil, err
+	}
+
+	m.exports = &VRecord{Fields: map[string]Value{}}
+	for name, value := range env.Values {
+		if !strings.HasPrefix(name, "_") {
+			m.exports.Fields[name] = value
+		}
+	}
+	return m.exports, nil
+}
+
+// loadModule evaluates the file at path in its own top-level Env.
+func (s *State) loadModule(path string) (*Env, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	src := lexer.NewSource(path, []rune(string(text)))
+	program, err := parser.ParseSource(src)
+	if err != nil {
+		return nil, err
+	}
+	env := NewEnv(s.builtins)
+	prog, err := s.compile(src, program, env)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.machine.Run(prog); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// resolveModule searches the directory of the running script and then ImportPath
+// for the file of the module name, and returns its absolute path.
+func (s *State) resolveModule(name string) (string, error) {
+	file := name
+	if filepath.Ext(file) != ".vv" {
+		file += ".vv"
+	}
+
+	var dirs []string
+	if src := s.machine.Source(); src != nil && src.Name != "" {
+		dirs = append(dirs, filepath.Dir(src.Name))
+	} else {
+		dirs = append(dirs, ".")
+	}
+	dirs = append(dirs, s.ImportPath...)
+
+	for _, dir := range dirs {
+		path, err := filepath.Abs(filepath.Join(dir, file))
+		if err != nil {
+			return "", err
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("module '%s' is not found", name)
+}
@@ -0,0 +1,155 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/lexer"
+)
+
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func evalFile(s *State, path string) error {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return s.EvalSource(lexer.NewSource(path, []rune(string(text))))
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.vv": `sprite = import('sprite')
+p = sprite.new('player', 1)
+sprite.move_by(p, 2)
+return [p.x, sprite.count]`,
+		"sprite.vv": `count = 0
+fun _incr()
+  count = count + 1
+end
+fun new(name, x)
+  _incr()
+  return { name = name, x = x }
+end
+fun move_by(sprite, dx)
+  sprite.x = sprite.x + dx
+end`,
+	})
+	s := NewState(WithCapabilities(CapCore, CapIO))
+	if err := evalFile(s, filepath.Join(dir, "main.vv")); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	// exports are the bindings when the module finished loading
+	expected := &VList{Elements: []Value{VNumber(3), VNumber(0)}}
+	if eq, _ := expected.Equal(v); !eq {
+		t.Fatalf("expected %s, but got %s", expected, v)
+	}
+}
+
+func TestImportExportsPublicBindingsOnly(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.vv": `return import('lib')`,
+		"lib.vv":  `x = 1 _y = 2 fun _f() end fun g() return _y end`,
+	})
+	s := NewState(WithCapabilities(CapCore, CapIO))
+	if err := evalFile(s, filepath.Join(dir, "main.vv")); err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := s.RetVals.Pop().(*VRecord)
+	if !ok {
+		t.Fatal("expected record")
+	}
+	if len(rec.Fields) != 2 || rec.Fields["x"] != VInt(1) || rec.Fields["g"] == nil {
+		t.Fatalf("unexpected exports: %s", rec)
+	}
+}
+
+func TestImportOnce(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.vv": `a = import('lib') b = import('lib.vv') return [a, b]`,
+		"lib.vv":  `x = 1`,
+	})
+	s := NewState(WithCapabilities(CapCore, CapIO))
+	if err := evalFile(s, filepath.Join(dir, "main.vv")); err != nil {
+		t.Fatal(err)
+	}
+	list := s.RetVals.Pop().(*VList)
+	if list.Elements[0] != list.Elements[1] {
+		t.Fatal("expected the same record for the same module")
+	}
+}
+
+func TestImportHasOwnGlobals(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"main.vv": `x = 'main' lib = import('lib') return [x, lib.get()]`,
+		"lib.vv":  `x = 'lib' fun get() return x end`,
+	})
+	s := NewState(WithCapabilities(CapCore, CapIO))
+	if err := evalFile(s, filepath.Join(dir, "main.vv")); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	expected := &VList{Elements: []Value{VString("main"), VString("lib")}}
+	if eq, _ := expected.Equal(v); !eq {
+		t.Fatalf("expected %s, but got %s", expected, v)
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	lib := writeModules(t, map[string]string{
+		"util/math.vv": `fun double(x) return x * 2 end`,
+	})
+	s := NewState(WithCapabilities(CapCore, CapIO))
+	s.ImportPath = []string{lib}
+	if err := s.Eval([]rune(`return import('util/math').double(4)`)); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.RetVals.Pop(); v != VInt(8) {
+		t.Fatalf("expected 8, but got %v", v)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"cycle.vv":   `import('a')`,
+		"a.vv":       `import('b')`,
+		"b.vv":       `import('a')`,
+		"missing.vv": `import('nothing')`,
+		"broken.vv":  `import('syntax')`,
+		"syntax.vv":  `x = )`,
+	})
+	tests := []struct {
+		file string
+		msg  string
+	}{
+		{"cycle.vv", "import cycle: " + filepath.Join(dir, "a.vv") + " -> " + filepath.Join(dir, "b.vv") + " -> " + filepath.Join(dir, "a.vv")},
+		{"missing.vv", "module 'nothing' is not found"},
+		{"broken.vv", filepath.Join(dir, "syntax.vv") + ":1:5: "},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore, CapIO))
+		err := evalFile(s, filepath.Join(dir, tt.file))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.file)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q in error, but got %q", tt.file, tt.msg, err.Error())
+		}
+	}
+}
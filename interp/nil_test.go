@@ -0,0 +1,142 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/value"
+)
+
+func TestNil(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return nil", nil},
+		{"f = fun() end\nreturn f()", nil},
+		{"f = fun() return end\nreturn f()", nil},
+		{"f = fun(x)\nif x\nreturn\nend\nreturn 1\nend\nreturn f(true)", nil},
+		{"return nil == nil", VBool(true)},
+		{"return 1 == nil", VBool(false)},
+		{"return nil == 'nil'", VBool(false)},
+		{"return nil != 1", VBool(true)},
+		{"return [nil] == [nil]", VBool(true)},
+		// nil nested in lists and records is comparable in either order
+		{"return [nil] == [1]", VBool(false)},
+		{"return [1] == [nil]", VBool(false)},
+		{"return ['a', true] == [nil, nil]", VBool(false)},
+		{"return {a = 1} == {a = nil}", VBool(false)},
+		{"return {a = nil} == {a = 1}", VBool(false)},
+		{"return [{a = [1]}] != [{a = [nil]}]", VBool(true)},
+		{"return [range(1)] == [nil]", VBool(false)},
+		{"return type == nil", VBool(false)},
+		{"return type == type", VBool(true)},
+		{"return type == len", VBool(false)},
+		{"return bool(nil)", VBool(false)},
+		{"return string(nil)", VString("nil")},
+		{"return type(nil)", VString("nil")},
+		{`return "{nil}"`, VString("nil")},
+		{"x = nil\nreturn x?.y", nil},
+		{"x = {}\nreturn x?.y", nil},
+		{"x = {y = 1}\nreturn x?.y", VNumber(1)},
+		{"x = {y = {}}\nreturn x?.y?.z", nil},
+		{"x = {}\nreturn x?.y?.z", nil},
+		{"x = {y = {z = 2}}\nreturn x?.y?.z", VNumber(2)},
+		// nil skips the rest of the chain
+		{"a = nil\nreturn a?.b.c", nil},
+		{"a = nil\nreturn a?.b.c(1)[0][1:]", nil},
+		{"a = {b = nil}\nreturn a?.b?.c.d", nil},
+		{"a = nil\nreturn [a?.b.c, 1]", &VList{Elements: []Value{VNil{}, VInt(1)}}},
+		{"a = {b = {c = [1, 2]}}\nreturn a?.b.c[1]", VInt(2)},
+		{"x = [1, 2]\nreturn find(x, fun(e) return e == 3 end) == nil", VBool(true)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if tt.expected == nil {
+			// a nil result is not left on RetVals
+			if s.RetVals.Len() != 0 {
+				t.Fatalf("%s: expected nil, got %v", tt.text, s.RetVals.Pop())
+			}
+			continue
+		}
+		v := s.RetVals.Pop()
+		eq, err := value.Equal(v, tt.expected)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if !eq {
+			t.Fatalf("%s: expected %v, got %v", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestNilValue(t *testing.T) {
+	s := NewState()
+	if err := s.Eval([]rune("f = fun() end\nx = [f(), nil]")); err != nil {
+		t.Fatal(err)
+	}
+	x, err := s.Global("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range x.(*VList).Elements {
+		if v != (VNil{}) {
+			t.Fatalf("expected nil, got %#v", v)
+		}
+	}
+}
+
+func TestMatchNil(t *testing.T) {
+	s := NewState()
+	text := `
+f = fun(x)
+	match x
+	case nil
+		return 'nil'
+	case _
+		return 'other'
+	end
+end
+return [f(nil), f(0)]
+`
+	if err := s.Eval([]rune(text)); err != nil {
+		t.Fatal(err)
+	}
+	v := s.RetVals.Pop()
+	if v.String() != `["nil", "other"]` {
+		t.Fatalf(`expected ["nil", "other"], got %s`, v)
+	}
+}
+
+func TestNilErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"x = nil\nreturn x.y", "cannot access field on non-record value of type nil"},
+		// only nil and missing fields are absorbed
+		{"x = 1\nreturn x?.y", "cannot access field on non-record value of type int"},
+		{"x = 'a'\nreturn x?.y", "cannot access field on non-record value of type string"},
+		{"x = [{}]\nreturn x?.y", "cannot access field on non-record value of type list"},
+		// a missing field does not skip the rest of the chain
+		{"x = {}\nreturn x?.y.z", "cannot access field on non-record value of type nil"},
+		{"return nil < 1", "unable to compare nil"},
+		{"return nil + 1", "left side value of add expression is not a number"},
+		{"return number(nil)", "unable to convert nil to number"},
+		{"return len(nil)", "argument for len() is expected string or array, but got nil"},
+		{"return nil()", "unable to call nil"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error, got nil", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Fatalf("%s: expected %q, got %q", tt.text, tt.expected, err)
+		}
+	}
+}
@@ -31,11 +31,11 @@ func TestRecordLiteralEval(t *testing.T) {
 	if !ok {
 		t.Fatalf("missing field 'key'")
 	}
-	kv, ok := keyVal.(VNumber)
+	kv, ok := keyVal.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber for key, got %T", keyVal)
+		t.Fatalf("expected VInt for key, got %T", keyVal)
 	}
-	if kv != VNumber(8) {
+	if kv != VInt(8) {
 		t.Fatalf("expected 8, got %v", kv)
 	}
 }
@@ -78,11 +78,11 @@ func TestRecordFieldAccessNumber(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	vn, ok := v.(VNumber)
+	vn, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
-	if vn != VNumber(8) {
+	if vn != VInt(8) {
 		t.Fatalf("expected 8, got %v", vn)
 	}
 }
@@ -105,20 +105,20 @@ func TestRecordSpread(t *testing.T) {
 	// Check that spread fields are present
 	if val, ok := r.Fields["a"]; !ok {
 		t.Fatalf("missing field 'a' from spread")
-	} else if vn, ok := val.(VNumber); !ok || vn != VNumber(1) {
+	} else if vn, ok := val.(VInt); !ok || vn != VInt(1) {
 		t.Fatalf("expected field 'a' to be 1, got %v", val)
 	}
 	
 	if val, ok := r.Fields["b"]; !ok {
 		t.Fatalf("missing field 'b' from spread")
-	} else if vn, ok := val.(VNumber); !ok || vn != VNumber(2) {
+	} else if vn, ok := val.(VInt); !ok || vn != VInt(2) {
 		t.Fatalf("expected field 'b' to be 2, got %v", val)
 	}
 	
 	// Check new field
 	if val, ok := r.Fields["c"]; !ok {
 		t.Fatalf("missing field 'c'")
-	} else if vn, ok := val.(VNumber); !ok || vn != VNumber(3) {
+	} else if vn, ok := val.(VInt); !ok || vn != VInt(3) {
 		t.Fatalf("expected field 'c' to be 3, got %v", val)
 	}
 }
@@ -130,11 +130,11 @@ func TestRecordSpreadOverride(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	vn, ok := v.(VNumber)
+	vn, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
-	if vn != VNumber(20) {
+	if vn != VInt(20) {
 		t.Fatalf("expected 20 (overridden value), got %v", vn)
 	}
 }
@@ -163,7 +163,7 @@ func TestRecordMultipleSpreads(t *testing.T) {
 	for fieldName, expectedValue := range expectedFields {
 		if val, ok := r.Fields[fieldName]; !ok {
 			t.Fatalf("missing field '%s'", fieldName)
-		} else if vn, ok := val.(VNumber); !ok || vn != VNumber(expectedValue) {
+		} else if vn, ok := val.(VInt); !ok || vn != VInt(expectedValue) {
 			t.Fatalf("expected field '%s' to be %v, got %v", fieldName, expectedValue, val)
 		}
 	}
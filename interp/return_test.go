@@ -8,9 +8,9 @@ func TestTopLevelReturnValue(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	n, ok := v.(VNumber)
+	n, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
 	if n != 1 {
 		t.Fatalf("expected 1, got %v", n)
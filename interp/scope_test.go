@@ -0,0 +1,76 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/parser"
+)
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected VInt
+	}{
+		// assigning to a global inside a function updates it
+		{"x = 0 fun incr() x = x + 1 end incr() incr() return x", 2},
+		// globals assigned after the function is defined are visible
+		{"fun get() return x end x = 5 return get()", 5},
+		// locals of a function do not overwrite globals of other names
+		{"y = 1 fun f() z = 10 return z end return f() + y", 11},
+		// nested functions share variables of the enclosing function
+		{"fun f() n = 1 fun g() n = n + 1 end g() g() return n end return f()", 3},
+		// recursion
+		{"fun fib(n) if n < 2 return n end return fib(n - 1) + fib(n - 2) end return fib(10)", 55},
+		// a function without return value can be assigned
+		{"fun g() end fun f() x = g() return 1 end return f()", 1},
+		// arguments are bound to parameters in order
+		{"fun f(a, b) return a - b end return f(3, 1)", 2},
+	}
+	for _, tt := range tests {
+		s := NewState()
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if v != tt.expected {
+			t.Fatalf("%s: expected %v, but got %v", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestLocalDoesNotLeak(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("fun f() y = 1 return y end f() return y"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "variable named 'y' is not found") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestLocalReadBeforeAssign(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("fun f() if false y = 1 end return y end return f()"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "variable named 'y' is not found") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	s := NewState()
+	err := s.Eval([]rune("x = 1\nbreak"))
+	var serr *parser.SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected SyntaxError, but got %v", err)
+	}
+	expected := "2:1: break outside of loop\nbreak\n^^^^^"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, but got %q", expected, err.Error())
+	}
+}
@@ -0,0 +1,144 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func strs(xs ...string) *VList {
+	elements := make([]Value, len(xs))
+	for i, x := range xs {
+		elements[i] = VString(x)
+	}
+	return &VList{Elements: elements}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"split('a,b,,c', ',')", strs("a", "b", "", "c")},
+		{"split('日本語', '')", strs("日", "本", "語")},
+		{"split('', ',')", strs("")},
+		{"join(['a', 'b', 'c'], ', ')", VString("a, b, c")},
+		{"join([], ',')", VString("")},
+		{"trim('  a b \t\n')", VString("a b")},
+		{"trim('　全角　')", VString("全角")},
+		{"trim_start('  a  ')", VString("a  ")},
+		{"trim_end('  a  ')", VString("  a")},
+		{"upper('abc Ä')", VString("ABC Ä")},
+		{"lower('ABC Ä')", VString("abc ä")},
+		{"find('hello', 'l')", VInt(2)},
+		{"find('hello', 'x')", VInt(-1)},
+		{"find('日本語です', 'です')", VInt(3)},
+		{"find('abc', '')", VInt(0)},
+		{"replace('a-b-c', '-', '+')", VString("a+b+c")},
+		{"replace('ねこねこ', 'ね', 'い')", VString("いこいこ")},
+		{"starts_with('vvlang', 'vv')", VBool(true)},
+		{"starts_with('vvlang', 'lang')", VBool(false)},
+		{"ends_with('vvlang', 'lang')", VBool(true)},
+		{"ends_with('vvlang', 'vv')", VBool(false)},
+		{"repeat('ab', 3)", VString("ababab")},
+		{"repeat('ab', 0)", VString("")},
+		{"chars('a😀b')", strs("a", "😀", "b")},
+		{"chars('')", strs()},
+		{"ord('a')", VInt(97)},
+		{"ord('😀')", VInt(0x1F600)},
+		{"chr(97)", VString("a")},
+		{"chr(12354)", VString("あ")},
+		{"pad_left('7', 3)", VString("  7")},
+		{"pad_left('7', 3, '0')", VString("007")},
+		{"pad_left('日本', 4, '・')", VString("・・日本")},
+		{"pad_left('long', 2)", VString("long")},
+		{"pad_right('ab', 4, '.')", VString("ab..")},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapString))
+		if err := s.Eval([]rune("return " + tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestStringBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"split('a')", "too many / less arguments for split()"},
+		{"split(1, ',')", "first argument for split() is expected string, but got int"},
+		{"join('abc', '')", "first argument for join() is expected list, but got string"},
+		{"join(['a', 1], '')", "element 1 for join() is expected string, but got int"},
+		{"upper(1)", "argument for upper() is expected string, but got int"},
+		{"repeat('a', 1.5)", "second argument for repeat() is expected int, but got number"},
+		{"repeat('a', -1)", "count for repeat() must not be negative, but got -1"},
+		{"ord('ab')", "argument for ord() is expected a single character, but got 'ab'"},
+		{"ord('')", "argument for ord() is expected a single character, but got ''"},
+		{"chr(-1)", "invalid character code -1 for chr()"},
+		{"chr(55296)", "invalid character code 55296 for chr()"},
+		{"pad_left('a', 3, '--')", "third argument for pad_left() is expected a single character, but got '--'"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapString))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, err.Error())
+		}
+	}
+}
+
+func TestRepeatAllocLimit(t *testing.T) {
+	s := NewState(WithCapabilities(CapString), WithMaxAllocs(1000))
+	err := s.Eval([]rune("repeat('abc', 1000000)"))
+	expectLimitError(t, err, AllocLimit)
+}
+
+func TestStringIndexByRunes(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return 'héllo'[1]", VString("é")},
+		{"return 'héllo'[-1]", VString("o")},
+		{"return 'héllo'[1:3]", VString("él")},
+		{"return 'こんにちは'[0]", VString("こ")},
+		{"return 'こんにちは'[-2]", VString("ち")},
+		{"return 'こんにちは'[1:3]", VString("んに")},
+		{"return 'こんにちは'[3:]", VString("ちは")},
+		{"return 'a😀b'[1]", VString("😀")},
+		{"return 'a😀b'[2]", VString("b")},
+		{"return 'a😀b'[:-1]", VString("a😀")},
+		{"s = '日本語テキスト' return s[len(s) - 1]", VString("ト")},
+		{"s = '🍣🍺' return len(s[1:])", VInt(1)},
+		{"s = 'ねこ' r = '' for c in s r = \"{c}{r}\" end return r", VString("こね")},
+		{"s = 'ねこ' r = [] for i in range(len(s)) push(r, s[i]) end return r", strs("ね", "こ")},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := tt.expected.Equal(v); err != nil || !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestStringIndexOutOfRange(t *testing.T) {
+	for _, text := range []string{"return 'こんにちは'[5]", "return '😀'[1]", "return '😀'[-2]"} {
+		s := NewState()
+		err := s.Eval([]rune(text))
+		if err == nil || !strings.Contains(err.Error(), "string index out of range") {
+			t.Fatalf("%s: expected out of range error, got %v", text, err)
+		}
+	}
+}
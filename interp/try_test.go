@@ -0,0 +1,146 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/value"
+)
+
+func TestTry(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		// thrown strings are caught as errors
+		{"try throw 'oops' catch e return e.message end", VString("oops")},
+		{"try throw 'oops' catch e return e.kind end", VString("error")},
+		{"try\nx = 1\n  throw 'oops'\ncatch e return [e.line, e.column] end", listOf(VInt(3), VInt(3))},
+		{"try throw error('gone', 'lookup') catch e return e.kind end", VString("lookup")},
+		{"try x = 1 catch e x = 2 end return x", VInt(1)},
+		// errors raised by operators and builtins are caught, too
+		{"try x = number('abc') catch e return e.kind end", VString("runtime")},
+		{"try x = {a = 1}.b catch e return e.message end", VString("record does not have field 'b'")},
+		{"try x = 1 div 0 catch e return e.message end", VString(ErrDivisionByZero.Error())},
+		// errors cross function calls
+		{"fun f() throw 'deep' end try f() catch e return e.message end", VString("deep")},
+		{"fun f() try throw 'a' catch e return 1 end return 2 end return f()", VInt(1)},
+		// finally runs on every exit
+		{`s = "" try s = "{s}1" finally s = "{s}2" end return s`, VString("12")},
+		{`s = "" try throw 'x' catch e s = "{s}1" finally s = "{s}2" end return s`, VString("12")},
+		{`s = "" fun f() try return 1 finally s = "{s}2" end end return [f(), s]`, listOf(VInt(1), VString("2"))},
+		{"n = 0 for x in [1, 2, 3] try if x == 2\nbreak\nend finally n = n + 1 end end return n", VInt(2)},
+		{"n = 0 for x in [1, 2, 3] try if x == 2\ncontinue\nend finally n = n + 1 end end return n", VInt(3)},
+		{`s = "" try try throw 'x' finally s = "{s}1" end catch e s = "{s}2" end return s`, VString("12")},
+		{`s = "" try try throw 'x' catch e throw 'y' finally s = "{s}1" end catch e s = "{s}{e.message}" end return s`, VString("1y")},
+		// errors thrown again keep their first position
+		{"try try\nthrow 'x' catch e throw e end catch e return e.line end", VInt(2)},
+		// errors are values
+		{"e = error('x') return type(e)", VString("error")},
+		{"e = error('x') return string(e)", VString("error: x")},
+		{"e = error('x') return e.line == nil", VBool(true)},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		if err := s.Eval([]rune(tt.text)); err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		v := s.RetVals.Pop()
+		if eq, err := value.Equal(v, tt.expected); err != nil || !eq {
+			t.Fatalf("%s: expected %s, got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestThrowUncaught(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"throw 'oops'", "1:1: oops"},
+		{"x = 1\nthrow error('gone')", "2:1: gone"},
+		{"try throw 'x' finally x = 1 end", "1:5: x"},
+		{"try throw 'x' catch e throw 'y' end", "1:23: y"},
+		{"throw 1", "1:1: value of throw is expected error or string, but got int"},
+	}
+	for _, tt := range tests {
+		s := NewState(WithCapabilities(CapCore))
+		err := s.Eval([]rune(tt.text))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Fatalf("%s: expected %q, got %q", tt.text, tt.expected, err)
+		}
+	}
+}
+
+func TestThrowFinallyRunsBeforeUncaught(t *testing.T) {
+	s := NewState()
+	if err := s.Eval([]rune("n = 0 try throw 'x' finally n = n + 1 end")); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := s.Eval([]rune("return n")); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.RetVals.Pop(); v != VInt(1) {
+		t.Fatalf("expected 1, got %s", v)
+	}
+}
+
+func TestLimitErrorNotCaught(t *testing.T) {
+	s := NewState(WithMaxSteps(1000))
+	err := s.Eval([]rune("try while true end catch e end"))
+	expectLimitError(t, err, StepLimit)
+
+	s = NewState()
+	err = s.Eval([]rune("fun f() return f() end try f() catch e end"))
+	var lerr *LimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected LimitError, but got %v", err)
+	}
+}
+
+func listOf(xs ...Value) *VList {
+	return &VList{Elements: xs}
+}
+
+type notFoundError struct {
+	name string
+}
+
+func (err *notFoundError) Error() string {
+	return err.name + " is not found"
+}
+
+func TestRethrowKeepsGoError(t *testing.T) {
+	tests := []string{
+		"try load('x') catch e throw e end",
+		"fun f() try load('x') catch e throw e end end try f() catch e throw e end",
+		"try load('x') finally end",
+	}
+	for _, text := range tests {
+		s := NewState()
+		s.RegisterGlobals(map[string]Value{
+			"load": Wrap(func(name string) error { return &notFoundError{name} }),
+		})
+		err := s.Eval([]rune(text))
+		var nerr *notFoundError
+		if !errors.As(err, &nerr) {
+			t.Fatalf("%s: expected *notFoundError, got %v", text, err)
+		}
+		if nerr.name != "x" {
+			t.Fatalf("%s: expected x, got %s", text, nerr.name)
+		}
+		var verr *VError
+		if !errors.As(err, &verr) || verr.Kind != ErrorKindRuntime {
+			t.Fatalf("%s: expected runtime *VError, got %v", text, err)
+		}
+	}
+
+	s := NewState()
+	if err := s.Eval([]rune("try x = 1 div 0 catch e throw e end")); !errors.Is(err, ErrDivisionByZero) {
+		t.Fatalf("expected division by zero, got %v", err)
+	}
+}
@@ -9,9 +9,9 @@ func TestWhileLoopIncrements(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	n, ok := v.(VNumber)
+	n, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
 	if n != 3 {
 		t.Fatalf("expected 3, got %v", n)
@@ -25,9 +25,9 @@ func TestWhileBreak(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	n, ok := v.(VNumber)
+	n, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
 	if n != 2 {
 		t.Fatalf("expected 2, got %v", n)
@@ -41,9 +41,9 @@ func TestWhileContinue(t *testing.T) {
 		t.Fatal(err)
 	}
 	v := s.RetVals.Pop()
-	n, ok := v.(VNumber)
+	n, ok := v.(VInt)
 	if !ok {
-		t.Fatalf("expected VNumber, got %T", v)
+		t.Fatalf("expected VInt, got %T", v)
 	}
 	if n != 4 {
 		t.Fatalf("expected 4, got %v", n)
@@ -0,0 +1,499 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fj68/vvlang/value"
+)
+
+var (
+	stateType = reflect.TypeOf((*State)(nil))
+	valueType = reflect.TypeOf((*Value)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Wrap converts a Go function into a builtin function.
+//
+// Arguments are converted from vv values to the parameter types of fn:
+// int and number to float and integer types, string, bool, list to slices and arrays,
+// record to maps with string keys and structs, and Value is passed as is.
+// fn may take *State as its first parameter and may be variadic.
+// The result is converted back into a vv value, and a trailing error
+// result or a panic of fn is reported as a runtime error.
+// Errors name the function by its Go name, e.g. `strings.Repeat`; see WrapNamed to name it otherwise.
+//
+// Wrap panics if fn is not a function or its signature cannot be converted.
+func Wrap(fn any) VBuiltinFun {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("interp.Wrap: expected function, but got %s", rv.Type()))
+	}
+	name := runtime.FuncForPC(rv.Pointer()).Name()
+	// e.g. github.com/fj68/vvlang/interp.Wrap is named interp.Wrap
+	return WrapNamed(name[strings.LastIndex(name, "/")+1:], fn)
+}
+
+// WrapNamed converts a Go function into a builtin function like Wrap,
+// naming it name in errors, e.g. the name of the global it is registered as.
+func WrapNamed(name string, fn any) VBuiltinFun {
+	rv := reflect.ValueOf(fn)
+	ty := rv.Type()
+	if ty.Kind() != reflect.Func {
+		panic(fmt.Sprintf("interp.Wrap: expected function, but got %s", ty))
+	}
+
+	withState := 0 < ty.NumIn() && ty.In(0) == stateType
+	params := make([]reflect.Type, 0, ty.NumIn())
+	for i := 0; i < ty.NumIn(); i++ {
+		if i == 0 && withState {
+			continue
+		}
+		t := ty.In(i)
+		if ty.IsVariadic() && i == ty.NumIn()-1 {
+			t = t.Elem()
+		}
+		if !convertible(t, nil) {
+			panic(fmt.Sprintf("interp.Wrap: unsupported parameter type %s", t))
+		}
+		params = append(params, t)
+	}
+
+	hasError := 0 < ty.NumOut() && ty.Out(ty.NumOut()-1) == errorType
+	results := ty.NumOut()
+	if hasError {
+		results--
+	}
+	if 1 < results {
+		panic(fmt.Sprintf("interp.Wrap: too many results of %s", ty))
+	}
+	if results == 1 && !convertible(ty.Out(0), nil) {
+		panic(fmt.Sprintf("interp.Wrap: unsupported result type %s", ty.Out(0)))
+	}
+
+	return func(s *State, args []Value) (Value, error) {
+		if ty.IsVariadic() {
+			if len(args) < len(params)-1 {
+				return nil, fmt.Errorf("expected at least %d arguments for %s(), but got %d", len(params)-1, name, len(args))
+			}
+		} else if len(args) != len(params) {
+			return nil, fmt.Errorf("expected %d arguments for %s(), but got %d", len(params), name, len(args))
+		}
+
+		in := make([]reflect.Value, 0, len(args)+1)
+		if withState {
+			in = append(in, reflect.ValueOf(s))
+		}
+		for i, arg := range args {
+			t := params[len(params)-1]
+			if i < len(params) {
+				t = params[i]
+			}
+			v, err := fromValue(arg, t, nil)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d for %s(): %w", i+1, name, err)
+			}
+			in = append(in, v)
+		}
+
+		out, err := call(name, rv, in)
+		if err != nil {
+			return nil, err
+		}
+		if hasError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+		if results == 0 {
+			return nil, nil
+		}
+		return toValue(out[0], nil)
+	}
+}
+
+// call calls fn named name with in, reporting a panic of fn as an error instead of crashing the host.
+func call(name string, fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("%s() panicked: %w", name, e)
+			} else {
+				err = fmt.Errorf("%s() panicked: %v", name, r)
+			}
+		}
+	}()
+	return fn.Call(in), nil
+}
+
+// ToValue converts a Go value into a vv value in the same way as results of wrapped functions.
+func ToValue(x any) (Value, error) {
+	if x == nil {
+		return VNil{}, nil
+	}
+	return toValue(reflect.ValueOf(x), nil)
+}
+
+// FromValue converts a vv value into a Go value of type T
+// in the same way as arguments of wrapped functions.
+func FromValue[T any](v Value) (T, error) {
+	var x T
+	t := reflect.TypeOf(&x).Elem()
+	if !convertible(t, nil) {
+		return x, fmt.Errorf("unsupported type %s", t)
+	}
+	rv, err := fromValue(v, t, nil)
+	if err != nil {
+		return x, err
+	}
+	reflect.ValueOf(&x).Elem().Set(rv)
+	return x, nil
+}
+
+// convertible reports whether values of t can be converted from and to vv values.
+// seen holds types being checked, which t can contain, e.g. `type Node struct{ Next *Node }`.
+func convertible(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == valueType {
+		return true
+	}
+	// the rest of the type decides
+	if seen[t] {
+		return true
+	}
+	if seen == nil {
+		seen = map[reflect.Type]bool{}
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		return convertible(t.Elem(), seen)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && convertible(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && !convertible(f.Type, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// fieldName returns the name of the record field f is converted from and to,
+// which is the `vv` tag or the field name starting with lower case.
+func fieldName(f reflect.StructField) string {
+	if name, ok := f.Tag.Lookup("vv"); ok {
+		return name
+	}
+	r, n := utf8.DecodeRuneInString(f.Name)
+	return string(unicode.ToLower(r)) + f.Name[n:]
+}
+
+// visited holds lists and records being converted by fromValue.
+type visited map[Value]bool
+
+func (seen visited) enter(v Value) visited {
+	if seen == nil {
+		seen = visited{}
+	}
+	seen[v] = true
+	return seen
+}
+
+// fromValue converts v into t. seen holds lists and records being converted, which cannot contain themselves.
+func fromValue(v Value, t reflect.Type, seen visited) (reflect.Value, error) {
+	if t == valueType {
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(&v).Elem(), nil
+	}
+	if _, ok := v.(VNil); ok || v == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s, but got nil", typeNameOf(t))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := v.(VBool); ok {
+			return reflect.ValueOf(bool(b)).Convert(t), nil
+		}
+	case reflect.String:
+		if str, ok := v.(VString); ok {
+			return reflect.ValueOf(string(str)).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := value.ToFloat(v); ok {
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := v.(VInt); ok {
+			if reflect.Zero(t).OverflowInt(int64(n)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, but got %s", t, v)
+			}
+			return reflect.ValueOf(int64(n)).Convert(t), nil
+		}
+		if n, ok := v.(VNumber); ok {
+			f := float64(n)
+			if f != math.Trunc(f) || f < math.MinInt64 || math.MaxInt64 <= f || reflect.Zero(t).OverflowInt(int64(f)) {
+				return reflect.Value{}, fmt.Errorf("expected int, but got %s", v)
+			}
+			return reflect.ValueOf(int64(f)).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := v.(VInt); ok {
+			if n < 0 || reflect.Zero(t).OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("expected non-negative int, but got %s", v)
+			}
+			return reflect.ValueOf(uint64(n)).Convert(t), nil
+		}
+		if n, ok := v.(VNumber); ok {
+			f := float64(n)
+			if f != math.Trunc(f) || f < 0 || math.MaxUint64 <= f || reflect.Zero(t).OverflowUint(uint64(f)) {
+				return reflect.Value{}, fmt.Errorf("expected non-negative int, but got %s", v)
+			}
+			return reflect.ValueOf(uint64(f)).Convert(t), nil
+		}
+	case reflect.Interface:
+		if natural := naturalTypeOf(v); natural != nil {
+			return fromValue(v, natural, seen)
+		}
+		// e.g. functions are passed as is
+		return reflect.ValueOf(v), nil
+	case reflect.Pointer:
+		elem, err := fromValue(v, t.Elem(), seen)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Slice, reflect.Array:
+		if list, ok := v.(*VList); ok {
+			if seen[list] {
+				return reflect.Value{}, fmt.Errorf("unable to convert list containing itself")
+			}
+			seen = seen.enter(list)
+			defer delete(seen, list)
+			var elems reflect.Value
+			if t.Kind() == reflect.Array {
+				if t.Len() != len(list.Elements) {
+					return reflect.Value{}, fmt.Errorf("expected list of %d elements, but got %d", t.Len(), len(list.Elements))
+				}
+				elems = reflect.New(t).Elem()
+			} else {
+				elems = reflect.MakeSlice(t, len(list.Elements), len(list.Elements))
+			}
+			for i, elem := range list.Elements {
+				ev, err := fromValue(elem, t.Elem(), seen)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+				}
+				elems.Index(i).Set(ev)
+			}
+			return elems, nil
+		}
+	case reflect.Map:
+		if rec, ok := v.(*VRecord); ok {
+			if seen[rec] {
+				return reflect.Value{}, fmt.Errorf("unable to convert record containing itself")
+			}
+			seen = seen.enter(rec)
+			defer delete(seen, rec)
+			m := reflect.MakeMapWithSize(t, len(rec.Fields))
+			for k, field := range rec.Fields {
+				fv, err := fromValue(field, t.Elem(), seen)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field '%s': %w", k, err)
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), fv)
+			}
+			return m, nil
+		}
+	case reflect.Struct:
+		if rec, ok := v.(*VRecord); ok {
+			if seen[rec] {
+				return reflect.Value{}, fmt.Errorf("unable to convert record containing itself")
+			}
+			seen = seen.enter(rec)
+			defer delete(seen, rec)
+			st := reflect.New(t).Elem()
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if !f.IsExported() {
+					continue
+				}
+				name := fieldName(f)
+				field, ok := rec.Fields[name]
+				if !ok {
+					continue
+				}
+				fv, err := fromValue(field, f.Type, seen)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field '%s': %w", name, err)
+				}
+				st.Field(i).Set(fv)
+			}
+			return st, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, but got %s", typeNameOf(t), v.Type())
+}
+
+// naturalTypeOf returns the Go type v is converted into when passed as `any`.
+func naturalTypeOf(v Value) reflect.Type {
+	switch v.Type() {
+	case VTBool:
+		return reflect.TypeOf(false)
+	case VTInt:
+		return reflect.TypeOf(int64(0))
+	case VTNumber:
+		return reflect.TypeOf(float64(0))
+	case VTString:
+		return reflect.TypeOf("")
+	case VTList:
+		return reflect.TypeOf([]any{})
+	case VTRecord:
+		return reflect.TypeOf(map[string]any{})
+	}
+	return nil
+}
+
+// typeNameOf returns the name of vv type which is converted into t.
+func typeNameOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "record"
+	case reflect.Pointer:
+		return typeNameOf(t.Elem())
+	}
+	return t.String()
+}
+
+// goRef is a pointer, map or slice of Go being converted by toValue.
+type goRef struct {
+	t reflect.Type
+	p uintptr
+}
+
+// toValue converts rv into a vv value. seen holds pointers, maps and slices being converted,
+// which cannot contain themselves.
+func toValue(rv reflect.Value, seen map[goRef]bool) (Value, error) {
+	if rv.Type() == valueType || rv.Type().Implements(valueType) {
+		if isNil(rv) {
+			return VNil{}, nil
+		}
+		return rv.Interface().(Value), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() || rv.Kind() == reflect.Slice && rv.Len() == 0 {
+			break
+		}
+		ref := goRef{rv.Type(), rv.Pointer()}
+		if seen[ref] {
+			return nil, fmt.Errorf("unable to convert %s containing itself", rv.Type())
+		}
+		if seen == nil {
+			seen = map[goRef]bool{}
+		}
+		seen[ref] = true
+		defer delete(seen, ref)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return VBool(rv.Bool()), nil
+	case reflect.String:
+		return VString(rv.String()), nil
+	case reflect.Float32, reflect.Float64:
+		return VNumber(rv.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return VInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return VInt(rv.Uint()), nil
+		}
+		return VNumber(rv.Uint()), nil
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return VNil{}, nil
+		}
+		return toValue(rv.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		elements := make([]Value, rv.Len())
+		for i := range elements {
+			elem, err := toValue(rv.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &VList{Elements: elements}, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		fields := make(map[string]Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			field, err := toValue(iter.Value(), seen)
+			if err != nil {
+				return nil, err
+			}
+			fields[iter.Key().String()] = field
+		}
+		return &VRecord{Fields: fields}, nil
+	case reflect.Struct:
+		fields := map[string]Value{}
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			field, err := toValue(rv.Field(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			fields[fieldName(f)] = field
+		}
+		return &VRecord{Fields: fields}, nil
+	}
+	return nil, fmt.Errorf("unable to convert %s to value", rv.Type())
+}
+
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
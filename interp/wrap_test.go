@@ -0,0 +1,232 @@
+package interp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X     float64
+	Y     float64
+	Label string `vv:"name"`
+}
+
+func evalWith(t *testing.T, globals map[string]Value, text string) (Value, error) {
+	t.Helper()
+	s := NewState()
+	s.RegisterGlobals(globals)
+	if err := s.Eval([]rune(text)); err != nil {
+		return nil, err
+	}
+	return s.RetVals.Pop(), nil
+}
+
+func TestWrap(t *testing.T) {
+	globals := map[string]Value{
+		"add":    Wrap(func(a, b float64) float64 { return a + b }),
+		"repeat": Wrap(func(s string, n int) string { return strings.Repeat(s, n) }),
+		"not":    Wrap(func(b bool) bool { return !b }),
+		"sum": Wrap(func(xs []int) int {
+			total := 0
+			for _, x := range xs {
+				total += x
+			}
+			return total
+		}),
+		"count": Wrap(func(xs ...any) int { return len(xs) }),
+		"keys": Wrap(func(m map[string]float64) []string {
+			var keys []string
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return keys
+		}),
+		"move": Wrap(func(p point, dx float64) point {
+			p.X += dx
+			return p
+		}),
+		"first": Wrap(func(xs []Value) Value { return xs[0] }),
+		"noop":  Wrap(func() {}),
+		"next":  Wrap(func(id int64) int64 { return id + 1 }),
+		"swap":  Wrap(func(xs [2]string) [2]string { return [2]string{xs[1], xs[0]} }),
+		"env": Wrap(func(s *State, name string) (Value, error) {
+			return s.Env.Get(name)
+		}),
+	}
+	tests := []struct {
+		text     string
+		expected Value
+	}{
+		{"return add(1, 2)", VNumber(3)},
+		{"return repeat('ab', 3)", VString("ababab")},
+		{"return not(false)", VBool(true)},
+		{"return sum([1, 2, 3])", VNumber(6)},
+		{"return count()", VNumber(0)},
+		{"return count(1, 'a', [true])", VNumber(3)},
+		{"return keys({ a = 1 })", &VList{Elements: []Value{VString("a")}}},
+		{"return move({ x = 1, y = 2, name = 'p' }, 3)", &VRecord{Fields: map[string]Value{"x": VNumber(4), "y": VNumber(2), "name": VString("p")}}},
+		{"return first([fun() end, 1])", nil},
+		{"return noop()", nil},
+		{"return next(9007199254740993)", VInt(9007199254740994)},
+		{"return swap(['a', 'b'])", &VList{Elements: []Value{VString("b"), VString("a")}}},
+		{"x = 5 return env('x')", VNumber(5)},
+	}
+	for _, tt := range tests {
+		v, err := evalWith(t, globals, tt.text)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if tt.expected == nil {
+			continue
+		}
+		if eq, _ := tt.expected.Equal(v); !eq {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+var errNegative = errors.New("negative value")
+
+func TestWrapErrors(t *testing.T) {
+	globals := map[string]Value{
+		"add":   WrapNamed("add", func(a, b float64) float64 { return a + b }),
+		"at":    WrapNamed("at", func(xs []string, i int) string { return xs[i] }),
+		"fmt":   WrapNamed("fmt", func(format string, args ...float64) string { return format }),
+		"move":  WrapNamed("move", func(p point) float64 { return p.X }),
+		"count": WrapNamed("count", func(xs []any) int { return len(xs) }),
+		"pair":  WrapNamed("pair", func(xs [2]int) int { return xs[0] }),
+		"sqrt": WrapNamed("sqrt", func(x float64) (float64, error) {
+			if x < 0 {
+				return 0, errNegative
+			}
+			return x, nil
+		}),
+		"fail":  WrapNamed("fail", func() { panic(errNegative) }),
+		"upper": Wrap(strings.ToUpper),
+	}
+	tests := []struct {
+		text string
+		msg  string
+	}{
+		{"add(1)", "expected 2 arguments for add(), but got 1"},
+		{"add(1, 2, 3)", "expected 2 arguments for add(), but got 3"},
+		{"add(1, 'a')", "argument 2 for add(): expected number, but got string"},
+		{"at(['a'], 0.5)", "argument 2 for at(): expected int, but got 0.5"},
+		{"at(['a', 1], 0)", "argument 1 for at(): element 1: expected string, but got int"},
+		{"fmt()", "expected at least 1 arguments for fmt(), but got 0"},
+		{"fmt('', 1, true)", "argument 3 for fmt(): expected number, but got bool"},
+		{"move({ x = 'a' })", "argument 1 for move(): field 'x': expected number, but got string"},
+		{"pair([1])", "argument 1 for pair(): expected list of 2 elements, but got 1"},
+		{"sqrt(-1)", "negative value"},
+		{"xs = [1, 2] xs[1] = xs count(xs)", "argument 1 for count(): element 1: unable to convert list containing itself"},
+		{"upper(1)", "argument 1 for strings.ToUpper(): expected string, but got int"},
+		// panics of Go functions are runtime errors, too
+		{"at(['a'], 1)", "at() panicked: runtime error: index out of range [1] with length 1"},
+		{"fail()", "fail() panicked: negative value"},
+	}
+	for _, tt := range tests {
+		_, err := evalWith(t, globals, tt.text)
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.text)
+		}
+		var rerr *RuntimeError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("%s: expected RuntimeError, but got %T", tt.text, err)
+		}
+		if rerr.Err.Error() != tt.msg {
+			t.Fatalf("%s: expected %q, but got %q", tt.text, tt.msg, rerr.Err.Error())
+		}
+	}
+
+	for _, text := range []string{"sqrt(-1)", "fail()", "try fail() catch e throw e end"} {
+		_, err := evalWith(t, globals, text)
+		if !errors.Is(err, errNegative) {
+			t.Fatalf("%s: expected error to wrap Go error, but got %v", text, err)
+		}
+	}
+}
+
+func TestWrapPanicsOnUnsupportedSignature(t *testing.T) {
+	tests := []any{
+		1,
+		func(c chan int) {},
+		func() (int, int) { return 0, 0 },
+		func(m map[int]string) {},
+	}
+	for _, fn := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for %T", fn)
+				}
+			}()
+			Wrap(fn)
+		}()
+	}
+}
+
+func TestValueConversion(t *testing.T) {
+	v, err := ToValue(map[string]any{"xs": []int{1, 2}, "p": &point{X: 1, Label: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{p = {name = \"a\", x = 1, y = 0}, xs = [1, 2]}"
+	if v.String() != expected {
+		t.Fatalf("expected %s, but got %s", expected, v)
+	}
+
+	p, err := FromValue[point](v.(*VRecord).Fields["p"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{X: 1, Label: "a"}) {
+		t.Fatalf("unexpected %+v", p)
+	}
+
+	if _, err := FromValue[int](VNumber(1.5)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestWrapSelfReferentialType(t *testing.T) {
+	globals := map[string]Value{
+		// converting a type containing itself does not recurse endlessly
+		"push": Wrap(func(n *node, v int) *node { return &node{Value: v, Next: n} }),
+		"loop": Wrap(func() *node {
+			n := &node{Value: 1}
+			n.Next = n
+			return n
+		}),
+	}
+	v, err := evalWith(t, globals, "return push(push(nil, 1), 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{next = {next = nil, value = 1}, value = 2}"; v.String() != expected {
+		t.Fatalf("expected %s, but got %s", expected, v)
+	}
+	v, err = evalWith(t, globals, "return push({value = 1, next = {value = 2}}, 3).next.next.value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != VInt(2) {
+		t.Fatalf("expected 2, but got %s", v)
+	}
+
+	_, err = evalWith(t, globals, "loop()")
+	if err == nil || !strings.Contains(err.Error(), "unable to convert *interp.node containing itself") {
+		t.Fatalf("expected error of cyclic value, but got %v", err)
+	}
+
+	xs := []any{1, nil}
+	xs[1] = xs
+	if _, err := ToValue(xs); err == nil {
+		t.Fatal("expected error of cyclic slice")
+	}
+}
@@ -0,0 +1,63 @@
+package lexer
+
+import "testing"
+
+func TestInterpolatedLex(t *testing.T) {
+	text := `"{sprite.name}_{sprite.face}.png" + "a{ {x = '}'}.x }b\{c"`
+	expected := []*Token{
+		{TInterplated, "{sprite.name}_{sprite.face}.png", Pos{0, 33}},
+		{TPlus, "+", Pos{34, 35}},
+		{TInterplated, `a{ {x = '}'}.x }b\{c`, Pos{36, 58}},
+	}
+	lex := New([]rune(text))
+	for i := 0; ; i++ {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type == TEOF {
+			if i != len(expected) {
+				t.Fatalf("expected %d tokens, got %d", len(expected), i)
+			}
+			break
+		}
+		if !tok.Eq(expected[i]) {
+			t.Fatalf("%d\n\texpected: %s\n\tactual : %s", i, expected[i], tok)
+		}
+	}
+}
+
+func TestInterpolatedLexUnterminated(t *testing.T) {
+	for _, text := range []string{`"abc`, `"a{b"`, `"a{'}"`} {
+		lex := New([]rune(text))
+		if _, err := lex.Next(); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
+
+func TestSplitInterpolated(t *testing.T) {
+	text := []rune(`"x={x}\n\{y} {f({a = 1})}!"`)
+	texts, exprs, err := SplitInterpolated(text, Pos{0, len(text)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTexts := []string{"x=", "\n{y} ", "!"}
+	if len(texts) != len(expectedTexts) {
+		t.Fatalf("expected %d texts, got %d", len(expectedTexts), len(texts))
+	}
+	for i, s := range expectedTexts {
+		if texts[i] != s {
+			t.Fatalf("text %d: expected %q, got %q", i, s, texts[i])
+		}
+	}
+	expectedExprs := []string{"x", "f({a = 1})"}
+	if len(exprs) != len(expectedExprs) {
+		t.Fatalf("expected %d exprs, got %d", len(expectedExprs), len(exprs))
+	}
+	for i, s := range expectedExprs {
+		if actual := string(text[exprs[i].Start:exprs[i].End]); actual != s {
+			t.Fatalf("expr %d: expected %q, got %q", i, s, actual)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package lexer
 
 import (
 	"fmt"
+	"strings"
 	"unicode"
 )
 
@@ -13,6 +14,14 @@ func New(text []rune) *Lexer {
 	return &Lexer{NewScanner(text)}
 }
 
+// NewAt returns a lexer which starts reading text at offset,
+// so that positions of tokens are relative to the whole text.
+func NewAt(text []rune, offset int) *Lexer {
+	s := NewScanner(text)
+	s.Pos = Pos{offset, offset}
+	return &Lexer{s}
+}
+
 func (lex *Lexer) Next() (*Token, error) {
 	lex.skipWhitespacesAndComments()
 
@@ -55,6 +64,11 @@ func (lex *Lexer) Next() (*Token, error) {
 	return nil, fmt.Errorf("unexpected letter '%s'", string(r))
 }
 
+// Pos returns the range of text being scanned.
+func (lex *Lexer) Pos() Pos {
+	return lex.s.Pos.Copy()
+}
+
 func (lex *Lexer) newToken(ty TokenType) *Token {
 	text, pos := lex.s.Flush()
 	return &Token{
@@ -102,19 +116,31 @@ func (lex *Lexer) comment(start, end string) int {
 	return pos.End - pos.Start
 }
 
+// digit reads a number literal, e.g. `12`, `1_000`, `0.5`, `0xff` or `0b101`.
+// The parser checks its digits.
 func (lex *Lexer) digit() (*Token, error) {
-	for !lex.s.IsEOF() && unicode.IsDigit(lex.s.Current()) {
-		lex.s.Advance(1)
+	if prefix := lex.s.Peek(2); len(prefix) == 2 && prefix[0] == '0' && strings.ContainsRune("xXbBoO", rune(prefix[1])) {
+		lex.s.Advance(2)
+		for !lex.s.IsEOF() && IsIdentLetter(lex.s.Current()) {
+			lex.s.Advance(1)
+		}
+		return lex.newToken(TDigit), nil
 	}
+	lex.digits()
 	if lex.s.Current() == '.' {
 		lex.s.Advance(1)
-		for !lex.s.IsEOF() && unicode.IsDigit(lex.s.Current()) {
-			lex.s.Advance(1)
-		}
+		lex.digits()
 	}
 	return lex.newToken(TDigit), nil
 }
 
+// digits reads decimal digits, which may be separated by underscores.
+func (lex *Lexer) digits() {
+	for !lex.s.IsEOF() && (unicode.IsDigit(lex.s.Current()) || lex.s.Current() == '_') {
+		lex.s.Advance(1)
+	}
+}
+
 func (lex *Lexer) literal() (*Token, error) {
 	marker := lex.s.Current()
 	lex.s.Skip(1)
@@ -128,7 +154,7 @@ func (lex *Lexer) literal() (*Token, error) {
 	}
 
 	if lex.s.IsEOF() {
-		return nil, fmt.Errorf("unexpected eof while reading string literal: %s", lex.s.Pos)
+		return nil, fmt.Errorf("unexpected eof while reading string literal")
 	}
 
 	lex.s.Skip(1) // skip end marker
@@ -136,48 +162,116 @@ func (lex *Lexer) literal() (*Token, error) {
 	return lex.newToken(TLiteral), nil
 }
 
+// interpolated reads a double-quoted string which may embed `{expr}` segments.
+// The token text is kept as written so that the parser can split it with SplitInterpolated.
 func (lex *Lexer) interpolated() (*Token, error) {
-	marker := lex.s.Current()
-	lex.s.Skip(1)
-
-	for !lex.s.IsEOF() && lex.s.Current() != marker {
-		if lex.s.Current() == '\\' {
-			lex.scanEscapeSequence()
-		} else {
-			lex.s.Advance(1)
-		}
-	}
-
-	if lex.s.IsEOF() {
-		return nil, fmt.Errorf("unexpected eof while reading string literal: %s", lex.s.Pos)
+	end := skipQuoted(lex.s.Text, lex.s.Pos.End)
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected eof while reading string literal")
 	}
 
+	lex.s.Skip(1) // skip start marker
+	lex.s.Advance(end - lex.s.Pos.End - 1)
 	lex.s.Skip(1) // skip end marker
 
-	return lex.newToken(TLiteral), nil
+	return lex.newToken(TInterplated), nil
 }
 
 func (lex *Lexer) scanEscapeSequence() {
 	lex.s.Skip(1) // skip marker
+	lex.s.Replace(unescape(lex.s.Current()))
+}
 
-	r := lex.s.Current()
+func unescape(r rune) rune {
 	switch r {
 	case 't':
-		lex.s.Replace('\t')
-		return
+		return '\t'
 	case 'r':
-		lex.s.Replace('\r')
-		return
+		return '\r'
 	case 'n':
-		lex.s.Replace('\n')
-		return
+		return '\n'
 	case 'b':
-		lex.s.Replace('\b')
-		return
+		return '\b'
 	default:
-		lex.s.Replace(r)
-		return
+		return r
+	}
+}
+
+// skipQuoted returns the index just after the string literal starting at text[i],
+// or -1 if the literal is not terminated.
+func skipQuoted(text []rune, i int) int {
+	marker := text[i]
+	for i++; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == marker:
+			return i + 1
+		case text[i] == '{' && marker == '"':
+			end := skipBraces(text, i)
+			if end < 0 {
+				return -1
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// skipBraces returns the index just after the `}` matching the `{` at text[i],
+// or -1 if it is not closed.
+func skipBraces(text []rune, i int) int {
+	depth := 0
+	for i < len(text) {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '\'', '"':
+			end := skipQuoted(text, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	return -1
+}
+
+// SplitInterpolated splits the interpolated string literal at pos into its texts
+// and the positions of the embedded expressions. len(texts) is always len(exprs)+1.
+func SplitInterpolated(text []rune, pos Pos) ([]string, []Pos, error) {
+	var texts []string
+	var exprs []Pos
+	var b strings.Builder
+	end := pos.End - 1 // skip end marker
+	for i := pos.Start + 1; i < end; {
+		switch text[i] {
+		case '\\':
+			b.WriteRune(unescape(text[i+1]))
+			i += 2
+		case '{':
+			close := skipBraces(text, i)
+			if close < 0 || end < close {
+				return nil, nil, fmt.Errorf("unclosed '{' in string literal")
+			}
+			texts = append(texts, b.String())
+			b.Reset()
+			exprs = append(exprs, Pos{i + 1, close - 1})
+			i = close
+		default:
+			b.WriteRune(text[i])
+			i++
+		}
 	}
+	texts = append(texts, b.String())
+	return texts, exprs, nil
 }
 
 func (lex *Lexer) ident() (*Token, error) {
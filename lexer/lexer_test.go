@@ -32,3 +32,63 @@ func TestLexer(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerComparisons(t *testing.T) {
+	text := "a != b > c >= not d"
+	expected := []TokenType{TIdent, TNotEqual, TIdent, TGreater, TIdent, TGreaterEq, TNot, TIdent, TEOF}
+	lex := New([]rune(text))
+	for i, ty := range expected {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != ty {
+			t.Fatalf("%d: expected %s, but got %s", i, ty, tok)
+		}
+	}
+}
+
+func TestLexerNil(t *testing.T) {
+	text := "a?.b == nil"
+	expected := []TokenType{TIdent, TQuestionDot, TIdent, TEqual, TNil, TEOF}
+	lex := New([]rune(text))
+	for i, ty := range expected {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != ty {
+			t.Fatalf("%d: expected %s, but got %s", i, ty, tok)
+		}
+	}
+}
+
+func TestLexerNumbers(t *testing.T) {
+	text := "0xff 0b_101 1_000 1.5 7 div 2"
+	expected := []string{"0xff", "0b_101", "1_000", "1.5", "7", "div", "2"}
+	lex := New([]rune(text))
+	for i, s := range expected {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Text != s {
+			t.Fatalf("%d: expected %s, but got %s", i, s, tok)
+		}
+	}
+}
+
+func TestLexerTry(t *testing.T) {
+	text := "try throw e catch err finally end"
+	expected := []TokenType{TTry, TThrow, TIdent, TCatch, TIdent, TFinally, TEnd, TEOF}
+	lex := New([]rune(text))
+	for i, ty := range expected {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type != ty {
+			t.Fatalf("%d: expected %s, but got %s", i, ty, tok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source is a program text together with the name it was loaded from.
+type Source struct {
+	Name string
+	Text []rune
+}
+
+func NewSource(name string, text []rune) *Source {
+	return &Source{Name: name, Text: text}
+}
+
+// LineCol converts a rune offset into 1-based line and column numbers.
+func (src *Source) LineCol(offset int) (line int, col int) {
+	if offset > len(src.Text) {
+		offset = len(src.Text)
+	}
+	line, col = 1, 1
+	for _, r := range src.Text[:offset] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// Locate returns "name:line:col" for the start of pos.
+func (src *Source) Locate(pos Pos) string {
+	line, col := src.LineCol(pos.Start)
+	if src.Name == "" {
+		return fmt.Sprintf("%d:%d", line, col)
+	}
+	return fmt.Sprintf("%s:%d:%d", src.Name, line, col)
+}
+
+// Excerpt returns the source line containing the start of pos,
+// followed by a line of carets underlining pos.
+func (src *Source) Excerpt(pos Pos) string {
+	start := pos.Start
+	if start > len(src.Text) {
+		start = len(src.Text)
+	}
+	lineStart := start
+	for 0 < lineStart && src.Text[lineStart-1] != '\n' {
+		lineStart--
+	}
+	lineEnd := start
+	for lineEnd < len(src.Text) && src.Text[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	var b strings.Builder
+	b.WriteString(string(src.Text[lineStart:lineEnd]))
+	b.WriteRune('\n')
+	// keep tabs so that carets line up with the source line
+	for _, r := range src.Text[lineStart:start] {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	end := pos.End
+	if lineEnd < end {
+		end = lineEnd
+	}
+	width := end - start
+	if width < 1 {
+		width = 1
+	}
+	b.WriteString(strings.Repeat("^", width))
+	return b.String()
+}
@@ -0,0 +1,40 @@
+package lexer
+
+import "testing"
+
+func TestSourceLineCol(t *testing.T) {
+	src := NewSource("test.vv", []rune("x = 1\nyé = x +\n  z"))
+	tests := []struct {
+		offset int
+		line   int
+		col    int
+	}{
+		{0, 1, 1},
+		{4, 1, 5},
+		{6, 2, 1},
+		{11, 2, 6},
+		{17, 3, 3},
+	}
+	for _, tt := range tests {
+		line, col := src.LineCol(tt.offset)
+		if line != tt.line || col != tt.col {
+			t.Fatalf("offset %d: expected %d:%d, got %d:%d", tt.offset, tt.line, tt.col, line, col)
+		}
+	}
+}
+
+func TestSourceLocateAndExcerpt(t *testing.T) {
+	src := NewSource("test.vv", []rune("x = 1\ny = x + z\nprint(y)"))
+	pos := Pos{14, 15}
+	if loc := src.Locate(pos); loc != "test.vv:2:9" {
+		t.Fatalf("expected test.vv:2:9, got %s", loc)
+	}
+	expected := "y = x + z\n        ^"
+	if excerpt := src.Excerpt(pos); excerpt != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, excerpt)
+	}
+	expected = "y = x + z\n    ^^^^^"
+	if excerpt := src.Excerpt(Pos{10, 15}); excerpt != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, excerpt)
+	}
+}
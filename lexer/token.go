@@ -26,10 +26,21 @@ const (
 	TFalse
 	TIn
 	TMod
+	TDiv
 	TAnd
 	TOr
 	TBreak
 	TContinue
+	TFor
+	TElif
+	TMatch
+	TCase
+	TNot
+	TNil
+	TTry
+	TCatch
+	TFinally
+	TThrow
 
 	// symbols
 	TLessEq
@@ -50,6 +61,10 @@ const (
 	TDot
 	TColon
 	TEllipsis
+	TNotEqual
+	TGreater
+	TGreaterEq
+	TQuestionDot
 )
 
 func (ty TokenType) String() string {
@@ -88,10 +103,32 @@ func (ty TokenType) String() string {
 		return "In"
 	case TMod:
 		return "Mod"
+	case TDiv:
+		return "Div"
 	case TAnd:
 		return "And"
 	case TOr:
 		return "Or"
+	case TFor:
+		return "For"
+	case TElif:
+		return "Elif"
+	case TMatch:
+		return "Match"
+	case TCase:
+		return "Case"
+	case TNot:
+		return "Not"
+	case TNil:
+		return "Nil"
+	case TTry:
+		return "Try"
+	case TCatch:
+		return "Catch"
+	case TFinally:
+		return "Finally"
+	case TThrow:
+		return "Throw"
 
 	// symbols
 	case TLessEq:
@@ -130,6 +167,14 @@ func (ty TokenType) String() string {
 		return "Colon"
 	case TEllipsis:
 		return "Ellipsis"
+	case TNotEqual:
+		return "NotEqual"
+	case TGreater:
+		return "Greater"
+	case TGreaterEq:
+		return "GreaterEq"
+	case TQuestionDot:
+		return "QuestionDot"
 	}
 	return "Unknown"
 }
@@ -156,6 +201,7 @@ func (tok *Token) Eq(other *Token) bool {
 var Symbols = map[rune]TokenType{
 	'=': TAssign,
 	'<': TLess,
+	'>': TGreater,
 	',': TComma,
 	'(': TLParen,
 	')': TRParen,
@@ -175,6 +221,9 @@ var Symbols2 = map[string]TokenType{
 	"<=": TLessEq,
 	"==": TEqual,
 	"...": TEllipsis,
+	"!=":  TNotEqual,
+	">=":  TGreaterEq,
+	"?.":  TQuestionDot,
 }
 
 var Keywords = map[string]TokenType{
@@ -188,10 +237,21 @@ var Keywords = map[string]TokenType{
 	"false":    TFalse,
 	"in":       TIn,
 	"mod":      TMod,
+	"div":      TDiv,
 	"and":      TAnd,
 	"or":       TOr,
 	"break":    TBreak,
 	"continue": TContinue,
+	"for":      TFor,
+	"elif":     TElif,
+	"match":    TMatch,
+	"case":     TCase,
+	"not":      TNot,
+	"nil":      TNil,
+	"try":      TTry,
+	"catch":    TCatch,
+	"finally":  TFinally,
+	"throw":    TThrow,
 }
 
 var Comments = map[string]string{
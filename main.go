@@ -1,15 +1,26 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/fj68/vvlang/interp"
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/repl"
 )
 
+func newState() *interp.State {
+	// scripts run by the CLI are trusted, so that they can access files and environment
+	s := interp.NewState(interp.WithCapabilities(interp.AllCapabilities...))
+	s.ImportPath = filepath.SplitList(os.Getenv("VV_PATH"))
+	return s
+}
+
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("usage: main [path]")
+		runREPL()
 		return
 	}
 	path := os.Args[1]
@@ -18,10 +29,23 @@ func main() {
 		fmt.Println(err)
 		return
 	}
-	s := interp.NewState()
-	s.RegisterGlobals(interp.DefaultBuiltins)
-	if err := s.Eval([]rune(string(text))); err != nil {
+	s := newState()
+	if err := s.EvalSource(lexer.NewSource(path, []rune(string(text)))); err != nil {
 		fmt.Println(err)
+		var rerr *interp.RuntimeError
+		if errors.As(err, &rerr) && rerr.Traceback != nil {
+			fmt.Println(rerr.Traceback)
+		}
 		return
 	}
 }
+
+func runREPL() {
+	r := repl.New(os.Stdin, os.Stdout, newState)
+	if home, err := os.UserHomeDir(); err == nil {
+		r.HistoryFile = filepath.Join(home, ".vv_history")
+	}
+	if err := r.Run(); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fj68/vvlang/ast"
+)
+
+func TestParseArithmeticPrecedence(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{
+			"1 + 2 * 3",
+			`InfixExpr{"+", IntLiteralExpr{1}, InfixExpr{"*", IntLiteralExpr{2}, IntLiteralExpr{3}}}`,
+		},
+		{
+			"1 * 2 + 3",
+			`InfixExpr{"+", InfixExpr{"*", IntLiteralExpr{1}, IntLiteralExpr{2}}, IntLiteralExpr{3}}`,
+		},
+		{
+			"10 - 4 - 3",
+			`InfixExpr{"-", InfixExpr{"-", IntLiteralExpr{10}, IntLiteralExpr{4}}, IntLiteralExpr{3}}`,
+		},
+		{
+			"8 / 4 / 2",
+			`InfixExpr{"/", InfixExpr{"/", IntLiteralExpr{8}, IntLiteralExpr{4}}, IntLiteralExpr{2}}`,
+		},
+		{
+			"7 mod 3 + 1",
+			`InfixExpr{"+", InfixExpr{"mod", IntLiteralExpr{7}, IntLiteralExpr{3}}, IntLiteralExpr{1}}`,
+		},
+		{
+			"-2 * 3",
+			`InfixExpr{"*", PrefixExpr{"-", IntLiteralExpr{2}}, IntLiteralExpr{3}}`,
+		},
+		{
+			"1 + 2 < 2 * 3",
+			`InfixExpr{"<", InfixExpr{"+", IntLiteralExpr{1}, IntLiteralExpr{2}}, InfixExpr{"*", IntLiteralExpr{2}, IntLiteralExpr{3}}}`,
+		},
+		{
+			"7 div 2 * 3",
+			`InfixExpr{"*", InfixExpr{"div", IntLiteralExpr{7}, IntLiteralExpr{2}}, IntLiteralExpr{3}}`,
+		},
+		{
+			"1 <= 2 == true",
+			`InfixExpr{"==", InfixExpr{"<=", IntLiteralExpr{1}, IntLiteralExpr{2}}, BoolLiteralExpr{true}}`,
+		},
+	}
+	for _, tt := range tests {
+		program, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if len(program) != 1 {
+			t.Fatalf("%s: expected 1 stmt, got %d", tt.text, len(program))
+		}
+		stmt, ok := program[0].(*ast.ExprStmt)
+		if !ok {
+			t.Fatalf("%s: expected ExprStmt, got %T", tt.text, program[0])
+		}
+		if actual := stmt.Inspect(); actual != tt.expected {
+			t.Fatalf("%s\n\texpected: %s\n\tactual  : %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseNumberLiterals(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"0", `IntLiteralExpr{0}`},
+		{"42", `IntLiteralExpr{42}`},
+		{"007", `IntLiteralExpr{7}`},
+		{"1_000_000", `IntLiteralExpr{1000000}`},
+		{"0xff", `IntLiteralExpr{255}`},
+		{"0XFF_FF", `IntLiteralExpr{65535}`},
+		{"0b1010", `IntLiteralExpr{10}`},
+		{"0o17", `IntLiteralExpr{15}`},
+		{"9223372036854775807", `IntLiteralExpr{9223372036854775807}`},
+		{"1.5", `NumberLiteralExpr{1.5}`},
+		{"1_000.25", `NumberLiteralExpr{1000.25}`},
+	}
+	for _, tt := range tests {
+		program, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if actual := program[0].(*ast.ExprStmt).Inspect(); actual != tt.expected {
+			t.Fatalf("%s\n\texpected: %s\n\tactual  : %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"0x", "invalid number literal '0x'"},
+		{"0xfg", "invalid number literal '0xfg'"},
+		{"0b102", "invalid number literal '0b102'"},
+		{"1__0", "invalid number literal '1__0'"},
+		{"1_", "invalid number literal '1_'"},
+		{"0_1", "invalid number literal '0_1'"},
+		{"9223372036854775808", "integer literal '9223372036854775808' is out of range"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]rune(tt.text))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("%s: expected *SyntaxError, got %v", tt.text, err)
+		}
+		if serr.Msg != tt.expected {
+			t.Fatalf("%s: expected %q, got %q", tt.text, tt.expected, serr.Msg)
+		}
+	}
+}
@@ -23,3 +23,43 @@ func TestParseVarDecl(t *testing.T) {
 		t.Fatalf("expected name 'x', got %s", v.Name)
 	}
 }
+
+func TestParseFieldAndIndexAssign(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{
+			"sprite.x = sprite.x + dx",
+			`AssignStmt{FieldAccessExpr{VarRefExpr{"sprite"}.x}, InfixExpr{"+", FieldAccessExpr{VarRefExpr{"sprite"}.x}, VarRefExpr{"dx"}}}`,
+		},
+		{
+			"xs[i] = v",
+			`AssignStmt{IndexExpr{VarRefExpr{"xs"}[VarRefExpr{"i"}]}, VarRefExpr{"v"}}`,
+		},
+		{
+			"a.b[2].c = 1",
+			`AssignStmt{FieldAccessExpr{IndexExpr{FieldAccessExpr{VarRefExpr{"a"}.b}[IntLiteralExpr{2}]}.c}, IntLiteralExpr{1}}`,
+		},
+	}
+	for _, tt := range tests {
+		program, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if len(program) != 1 {
+			t.Fatalf("%s: expected 1 stmt, got %d", tt.text, len(program))
+		}
+		if actual := program[0].Inspect(); actual != tt.expected {
+			t.Fatalf("%s\n\texpected: %s\n\tactual  : %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseAssignInvalidTarget(t *testing.T) {
+	for _, text := range []string{"f() = 1", "1 = 2", "xs[1:2] = 3"} {
+		if _, err := Parse([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error for invalid assignment target", text)
+		}
+	}
+}
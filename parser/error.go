@@ -0,0 +1,21 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/fj68/vvlang/lexer"
+)
+
+// SyntaxError is an error found while lexing or parsing a source.
+type SyntaxError struct {
+	Source *lexer.Source
+	Pos    lexer.Pos
+	Msg    string
+}
+
+func (err *SyntaxError) Error() string {
+	if err.Source == nil {
+		return err.Msg
+	}
+	return fmt.Sprintf("%s: %s\n%s", err.Source.Locate(err.Pos), err.Msg, err.Source.Excerpt(err.Pos))
+}
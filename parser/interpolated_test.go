@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/fj68/vvlang/ast"
+	"github.com/fj68/vvlang/lexer"
+)
+
+func TestParseInterpolatedString(t *testing.T) {
+	text := `"{sprite.name}_{sprite.face + 1}.png"`
+	program, err := Parse([]rune(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := program[0].(*ast.ExprStmt)
+	expr, ok := stmt.Expr.(*ast.InterpolatedStringLiteralExpr)
+	if !ok {
+		t.Fatalf("expected InterpolatedStringLiteralExpr, got %T", stmt.Expr)
+	}
+	expected := `InterpolatedStringLiteralExpr{"{FieldAccessExpr{VarRefExpr{"sprite"}.name}}_{InfixExpr{"+", FieldAccessExpr{VarRefExpr{"sprite"}.face}, IntLiteralExpr{1}}}.png"}`
+	if actual := expr.Inspect(); actual != expected {
+		t.Fatalf("\n\texpected: %s\n\tactual  : %s", expected, actual)
+	}
+	// positions of embedded expressions point into the whole text
+	if !expr.Values[0].Span().Eq(lexer.Pos{Start: 2, End: 13}) {
+		t.Fatalf("unexpected span: %s", expr.Values[0].Span())
+	}
+}
+
+func TestParsePlainDoubleQuotedString(t *testing.T) {
+	program, err := Parse([]rune(`"no \{braces}\n"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := program[0].(*ast.ExprStmt)
+	lit, ok := stmt.Expr.(*ast.StringLiteralExpr)
+	if !ok {
+		t.Fatalf("expected StringLiteralExpr, got %T", stmt.Expr)
+	}
+	if lit.Value != "no {braces}\n" {
+		t.Fatalf("unexpected value: %q", lit.Value)
+	}
+}
+
+func TestParseInterpolatedStringErrors(t *testing.T) {
+	for _, text := range []string{`"{}"`, `"{a b}"`, `"{a +}"`} {
+		if _, err := Parse([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
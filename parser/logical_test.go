@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/fj68/vvlang/ast"
+)
+
+func TestParseLogicalPrecedence(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{
+			"a < 1 and b == 2",
+			`LogicalExpr{"and", InfixExpr{"<", VarRefExpr{"a"}, IntLiteralExpr{1}}, InfixExpr{"==", VarRefExpr{"b"}, IntLiteralExpr{2}}}`,
+		},
+		{
+			"a or b and c",
+			`LogicalExpr{"or", VarRefExpr{"a"}, LogicalExpr{"and", VarRefExpr{"b"}, VarRefExpr{"c"}}}`,
+		},
+		{
+			"a and b or c",
+			`LogicalExpr{"or", LogicalExpr{"and", VarRefExpr{"a"}, VarRefExpr{"b"}}, VarRefExpr{"c"}}`,
+		},
+		{
+			"a or b or c",
+			`LogicalExpr{"or", LogicalExpr{"or", VarRefExpr{"a"}, VarRefExpr{"b"}}, VarRefExpr{"c"}}`,
+		},
+		{
+			"not a == b",
+			`PrefixExpr{"not", InfixExpr{"==", VarRefExpr{"a"}, VarRefExpr{"b"}}}`,
+		},
+		{
+			"not a and not b",
+			`LogicalExpr{"and", PrefixExpr{"not", VarRefExpr{"a"}}, PrefixExpr{"not", VarRefExpr{"b"}}}`,
+		},
+		{
+			"a != 1 or b >= 2 and c > 3",
+			`LogicalExpr{"or", InfixExpr{"!=", VarRefExpr{"a"}, IntLiteralExpr{1}}, LogicalExpr{"and", InfixExpr{">=", VarRefExpr{"b"}, IntLiteralExpr{2}}, InfixExpr{">", VarRefExpr{"c"}, IntLiteralExpr{3}}}}`,
+		},
+		{
+			"a > b == c <= d",
+			`InfixExpr{"==", InfixExpr{">", VarRefExpr{"a"}, VarRefExpr{"b"}}, InfixExpr{"<=", VarRefExpr{"c"}, VarRefExpr{"d"}}}`,
+		},
+		{
+			"(a or b) and c",
+			`LogicalExpr{"and", LogicalExpr{"or", VarRefExpr{"a"}, VarRefExpr{"b"}}, VarRefExpr{"c"}}`,
+		},
+		{
+			"(1 + 2) * 3",
+			`InfixExpr{"*", InfixExpr{"+", IntLiteralExpr{1}, IntLiteralExpr{2}}, IntLiteralExpr{3}}`,
+		},
+	}
+	for _, tt := range tests {
+		program, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if len(program) != 1 {
+			t.Fatalf("%s: expected 1 stmt, got %d", tt.text, len(program))
+		}
+		stmt, ok := program[0].(*ast.ExprStmt)
+		if !ok {
+			t.Fatalf("%s: expected ExprStmt, got %T", tt.text, program[0])
+		}
+		if actual := stmt.Inspect(); actual != tt.expected {
+			t.Fatalf("%s\n\texpected: %s\n\tactual  : %s", tt.text, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fj68/vvlang/ast"
+)
+
+func TestParseNil(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"nil", `NilLiteralExpr{}`},
+		{"a == nil", `InfixExpr{"==", VarRefExpr{"a"}, NilLiteralExpr{}}`},
+		{"a?.b", `FieldAccessExpr{VarRefExpr{"a"}?.b}`},
+		{"a?.b.c", `FieldAccessExpr{FieldAccessExpr{VarRefExpr{"a"}?.b}.c}`},
+		{"a.b?.c", `FieldAccessExpr{FieldAccessExpr{VarRefExpr{"a"}.b}?.c}`},
+	}
+	for _, tt := range tests {
+		program, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		stmt, ok := program[0].(*ast.ExprStmt)
+		if !ok {
+			t.Fatalf("%s: expected ExprStmt, got %T", tt.text, program[0])
+		}
+		if actual := stmt.Inspect(); actual != tt.expected {
+			t.Fatalf("%s\n\texpected: %s\n\tactual  : %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseOptionalFieldAssign(t *testing.T) {
+	_, err := Parse([]rune("a?.b = 1"))
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *SyntaxError, got %T", err)
+	}
+	if serr.Msg != "cannot assign to optional field access" {
+		t.Fatalf("unexpected error: %s", serr.Msg)
+	}
+}
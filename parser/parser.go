@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/fj68/vvlang/ast"
 	"github.com/fj68/vvlang/lexer"
@@ -21,6 +23,9 @@ type Precedence int
 
 const (
 	PLowest Precedence = iota
+	POr
+	PAnd
+	PNot
 	PEquals
 	PLess
 	PSum
@@ -31,16 +36,25 @@ const (
 )
 
 var precedences = map[lexer.TokenType]Precedence{
-	lexer.TIdent:    PLowest,
-	lexer.TEqual:    PEquals,
-	lexer.TLess:     PLess,
-	lexer.TPlus:     PSum,
-	lexer.THyphen:   PSum,
-	lexer.TAsterisk: PProduct,
-	lexer.TSlash:    PProduct,
-	lexer.TLParen:   PCall,
-	lexer.TLBrace:   PIndex,
-	lexer.TDot:      PCall,
+	lexer.TIdent:       PLowest,
+	lexer.TOr:          POr,
+	lexer.TAnd:         PAnd,
+	lexer.TEqual:       PEquals,
+	lexer.TNotEqual:    PEquals,
+	lexer.TLess:        PLess,
+	lexer.TLessEq:      PLess,
+	lexer.TGreater:     PLess,
+	lexer.TGreaterEq:   PLess,
+	lexer.TPlus:        PSum,
+	lexer.THyphen:      PSum,
+	lexer.TAsterisk:    PProduct,
+	lexer.TSlash:       PProduct,
+	lexer.TMod:         PProduct,
+	lexer.TDiv:         PProduct,
+	lexer.TLParen:      PCall,
+	lexer.TLBrace:      PIndex,
+	lexer.TDot:         PCall,
+	lexer.TQuestionDot: PCall,
 }
 
 func precedenceOf(ty lexer.TokenType) Precedence {
@@ -54,7 +68,9 @@ type PrefixParser func() (ast.Expr, error)
 type InfixParser func(left ast.Expr) (ast.Expr, error)
 
 type Parser struct {
+	src       *lexer.Source
 	lex       *lexer.Lexer
+	prevToken *lexer.Token
 	curToken  *lexer.Token
 	peekToken *lexer.Token
 
@@ -63,8 +79,13 @@ type Parser struct {
 }
 
 func New(text []rune) *Parser {
+	return NewSource(lexer.NewSource("", text))
+}
+
+func NewSource(src *lexer.Source) *Parser {
 	p := &Parser{
-		lex: lexer.New(text),
+		src: src,
+		lex: lexer.New(src.Text),
 	}
 	p.registerPrefixParsers()
 	p.registerInfixParsers()
@@ -76,30 +97,49 @@ func Parse(text []rune) ([]ast.Stmt, error) {
 	return p.Parse()
 }
 
+func ParseSource(src *lexer.Source) ([]ast.Stmt, error) {
+	p := NewSource(src)
+	return p.Parse()
+}
+
 func (p *Parser) registerPrefixParsers() {
 	p.prefixParsers = map[lexer.TokenType]PrefixParser{
-		lexer.TDigit:    p.parseDigitLiteralExpr,
-		lexer.TTrue:     p.parseBoolLiteralExpr,
-		lexer.TFalse:    p.parseBoolLiteralExpr,
-		lexer.TLiteral:  p.parseStringLiteralExpr,
-		lexer.THyphen:   p.parsePrefixExpr,
-		lexer.TIdent:    p.parseVarRefExpr,
-		lexer.TFun:      p.parseFunLiteralExpr,
-		lexer.TLBrace:   p.parseListLiteralExpr,
-		lexer.TLBracket: p.parseRecordLiteralExpr,
+		lexer.TDigit:       p.parseDigitLiteralExpr,
+		lexer.TTrue:        p.parseBoolLiteralExpr,
+		lexer.TFalse:       p.parseBoolLiteralExpr,
+		lexer.TNil:         p.parseNilLiteralExpr,
+		lexer.TLiteral:     p.parseStringLiteralExpr,
+		lexer.TInterplated: p.parseInterpolatedStringLiteralExpr,
+		lexer.THyphen:      p.parsePrefixExpr,
+		lexer.TNot:         p.parseNotExpr,
+		lexer.TLParen:      p.parseGroupedExpr,
+		lexer.TIdent:       p.parseVarRefExpr,
+		lexer.TFun:         p.parseFunLiteralExpr,
+		lexer.TLBrace:      p.parseListLiteralExpr,
+		lexer.TLBracket:    p.parseRecordLiteralExpr,
 	}
 }
 
 func (p *Parser) registerInfixParsers() {
 	p.infixParsers = map[lexer.TokenType]InfixParser{
-		lexer.TDot:     p.parseFieldAccessExpr,
-		lexer.THyphen:  p.parseInfixExpr,
-		lexer.TPlus:    p.parseInfixExpr,
-		lexer.TEqual:   p.parseInfixExpr,
-		lexer.TLessEq:  p.parseInfixExpr,
-		lexer.TLess:    p.parseInfixExpr,
-		lexer.TLParen:  p.parseFunCallExpr,
-		lexer.TLBrace:  p.parseIndexOrSliceExpr,
+		lexer.TDot:         p.parseFieldAccessExpr,
+		lexer.TQuestionDot: p.parseFieldAccessExpr,
+		lexer.THyphen:      p.parseInfixExpr,
+		lexer.TPlus:        p.parseInfixExpr,
+		lexer.TAsterisk:    p.parseInfixExpr,
+		lexer.TSlash:       p.parseInfixExpr,
+		lexer.TMod:         p.parseInfixExpr,
+		lexer.TDiv:         p.parseInfixExpr,
+		lexer.TEqual:       p.parseInfixExpr,
+		lexer.TLessEq:      p.parseInfixExpr,
+		lexer.TLess:        p.parseInfixExpr,
+		lexer.TNotEqual:    p.parseInfixExpr,
+		lexer.TGreater:     p.parseInfixExpr,
+		lexer.TGreaterEq:   p.parseInfixExpr,
+		lexer.TAnd:         p.parseLogicalExpr,
+		lexer.TOr:          p.parseLogicalExpr,
+		lexer.TLParen:      p.parseFunCallExpr,
+		lexer.TLBrace:      p.parseIndexOrSliceExpr,
 	}
 }
 
@@ -107,11 +147,25 @@ func (p *Parser) Parse() ([]ast.Stmt, error) {
 	return p.parseProgram()
 }
 
+func (p *Parser) errorf(pos lexer.Pos, format string, args ...any) error {
+	return &SyntaxError{
+		Source: p.src,
+		Pos:    pos,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// span returns the range from start to the end of the last consumed token.
+func (p *Parser) span(start lexer.Pos) lexer.Pos {
+	return lexer.Pos{Start: start.Start, End: p.prevToken.Pos.End}
+}
+
 func (p *Parser) readToken() error {
 	tok, err := p.lex.Next()
 	if err != nil {
-		return err
+		return p.errorf(p.lex.Pos(), "%s", err)
 	}
+	p.prevToken = p.curToken
 	p.curToken = p.peekToken
 	p.peekToken = tok
 	return nil
@@ -119,7 +173,7 @@ func (p *Parser) readToken() error {
 
 func (p *Parser) expect(ty lexer.TokenType) error {
 	if p.curToken.Type != ty {
-		return fmt.Errorf("expected %s, but got %s", ty, p.curToken.Type)
+		return p.errorf(p.curToken.Pos, "expected %s, but got %s", ty, p.curToken.Type)
 	}
 	if err := p.readToken(); err != nil {
 		return err
@@ -129,7 +183,7 @@ func (p *Parser) expect(ty lexer.TokenType) error {
 
 func (p *Parser) expectNext(ty lexer.TokenType) error {
 	if p.peekToken.Type != ty {
-		return fmt.Errorf("expected %s, but got %s", ty, p.peekToken.Type)
+		return p.errorf(p.peekToken.Pos, "expected %s, but got %s", ty, p.peekToken.Type)
 	}
 	if err := p.readToken(); err != nil {
 		return err
@@ -161,7 +215,7 @@ func (p *Parser) parseProgram() ([]ast.Stmt, error) {
 
 func (p *Parser) parseStmt() (ast.Stmt, error) {
 	if p.curToken.Type == lexer.TEOF {
-		return nil, fmt.Errorf("unexpected EOF")
+		return nil, p.errorf(p.curToken.Pos, "unexpected EOF")
 	}
 
 	if p.curToken.Type == lexer.TIdent && p.peekToken.Type == lexer.TAssign {
@@ -173,10 +227,26 @@ func (p *Parser) parseStmt() (ast.Stmt, error) {
 		return p.parseWhileStmt()
 	}
 
+	if p.curToken.Type == lexer.TFor {
+		return p.parseForStmt()
+	}
+
+	if p.curToken.Type == lexer.TMatch {
+		return p.parseMatchStmt()
+	}
+
 	if p.curToken.Type == lexer.TIf {
 		return p.parseIfStmt()
 	}
 
+	if p.curToken.Type == lexer.TTry {
+		return p.parseTryStmt()
+	}
+
+	if p.curToken.Type == lexer.TThrow {
+		return p.parseThrowStmt()
+	}
+
 	if p.curToken.Type == lexer.TReturn {
 		return p.parseReturnStmt()
 	}
@@ -194,6 +264,11 @@ func (p *Parser) parseStmt() (ast.Stmt, error) {
 		return nil, err
 	}
 
+	if p.curToken.Type == lexer.TAssign {
+		// `r.x = expr` or `xs[i] = expr` form
+		return p.parseAssignStmt(expr)
+	}
+
 	return &ast.ExprStmt{Expr: expr}, nil
 }
 
@@ -213,16 +288,21 @@ func (p *Parser) parseBodyStmt() (ast.Stmt, error) {
 	return p.parseStmt()
 }
 
+// isEndOfBody reports whether ty starts the next clause of a statement, e.g. `else` or `catch`.
+func isEndOfBody(ty lexer.TokenType) bool {
+	return oneOf([]lexer.TokenType{lexer.TElse, lexer.TElif, lexer.TCase, lexer.TCatch, lexer.TFinally}, ty)
+}
+
 func (p *Parser) parseBody() ([]ast.Stmt, error) {
 	var body []ast.Stmt
 	for {
 		if p.curToken.Type == lexer.TEOF {
-			return nil, fmt.Errorf("unexpected eof while reading body")
+			return nil, p.errorf(p.curToken.Pos, "unexpected eof while reading body")
 		}
 		if p.curToken.Type == lexer.TEnd {
 			break
 		}
-		if p.curToken.Type == lexer.TElse {
+		if isEndOfBody(p.curToken.Type) {
 			break
 		}
 		stmt, err := p.parseBodyStmt()
@@ -235,26 +315,29 @@ func (p *Parser) parseBody() ([]ast.Stmt, error) {
 }
 
 func (p *Parser) parseBreakStmt() (*ast.BreakStmt, error) {
+	start := p.curToken.Pos
 	if err := p.expect(lexer.TBreak); err != nil {
 		return nil, err
 	}
-	return &ast.BreakStmt{}, nil
+	return &ast.BreakStmt{Pos: p.span(start)}, nil
 }
 
 func (p *Parser) parseContinueStmt() (*ast.ContinueStmt, error) {
+	start := p.curToken.Pos
 	if err := p.expect(lexer.TContinue); err != nil {
 		return nil, err
 	}
-	return &ast.ContinueStmt{}, nil
+	return &ast.ContinueStmt{Pos: p.span(start)}, nil
 }
 
 func (p *Parser) parseReturnStmt() (*ast.ReturnStmt, error) {
-	// allow `return` without a value (e.g. `return`, or `return` followed by `end`)
-	if p.peekToken.Type == lexer.TEnd || p.peekToken.Type == lexer.TEOF {
+	start := p.curToken.Pos
+	// allow `return` without a value (e.g. `return`, or `return` followed by `end` or `catch`)
+	if p.peekToken.Type == lexer.TEnd || p.peekToken.Type == lexer.TEOF || isEndOfBody(p.peekToken.Type) {
 		if err := p.readToken(); err != nil {
 			return nil, err
 		}
-		return &ast.ReturnStmt{}, nil
+		return &ast.ReturnStmt{Pos: p.span(start)}, nil
 	}
 	if err := p.readToken(); err != nil {
 		return nil, err
@@ -264,11 +347,13 @@ func (p *Parser) parseReturnStmt() (*ast.ReturnStmt, error) {
 		return nil, err
 	}
 	return &ast.ReturnStmt{
+		Pos:   p.span(start),
 		Value: expr,
 	}, nil
 }
 
 func (p *Parser) parseVarDeclStmt() (*ast.VarDeclStmt, error) {
+	start := p.curToken.Pos
 	name := p.curToken.Text
 
 	if err := p.expectNext(lexer.TAssign); err != nil {
@@ -285,12 +370,42 @@ func (p *Parser) parseVarDeclStmt() (*ast.VarDeclStmt, error) {
 	}
 
 	return &ast.VarDeclStmt{
+		Pos:  p.span(start),
 		Name: name,
 		Body: expr,
 	}, nil
 }
 
+func (p *Parser) parseAssignStmt(target ast.Expr) (*ast.AssignStmt, error) {
+	start := target.Span()
+	switch target := target.(type) {
+	case *ast.FieldAccessExpr:
+		if target.Optional {
+			return nil, p.errorf(target.Span(), "cannot assign to optional field access")
+		}
+	case *ast.IndexExpr:
+	default:
+		return nil, p.errorf(target.Span(), "cannot assign to %s", target.Inspect())
+	}
+
+	if err := p.expect(lexer.TAssign); err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.AssignStmt{
+		Pos:    p.span(start),
+		Target: target,
+		Value:  value,
+	}, nil
+}
+
 func (p *Parser) parseWhileStmt() (*ast.WhileStmt, error) {
+	start := p.curToken.Pos
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
@@ -306,12 +421,70 @@ func (p *Parser) parseWhileStmt() (*ast.WhileStmt, error) {
 		return nil, err
 	}
 	return &ast.WhileStmt{
+		Pos:  p.span(start),
 		Cond: cond,
 		Body: body,
 	}, nil
 }
 
+func (p *Parser) parseForStmt() (*ast.ForStmt, error) {
+	start := p.curToken.Pos
+	if err := p.expectNext(lexer.TIdent); err != nil {
+		return nil, err
+	}
+	key, value := "", p.curToken.Text
+	if p.peekToken.Type == lexer.TComma {
+		// `for key, value in` form
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		if err := p.expectNext(lexer.TIdent); err != nil {
+			return nil, err
+		}
+		key, value = value, p.curToken.Text
+	}
+	if err := p.expectNext(lexer.TIn); err != nil {
+		return nil, err
+	}
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	iter, err := p.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(lexer.TEnd); err != nil {
+		return nil, err
+	}
+	return &ast.ForStmt{
+		Pos:   p.span(start),
+		Key:   key,
+		Value: value,
+		Iter:  iter,
+		Body:  body,
+	}, nil
+}
+
 func (p *Parser) parseIfStmt() (*ast.IfStmt, error) {
+	start := p.curToken.Pos
+	stmt, err := p.parseIfClauses()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(lexer.TEnd); err != nil {
+		return nil, err
+	}
+	stmt.Pos = p.span(start)
+	return stmt, nil
+}
+
+// parseIfClauses parses `if` or `elif` clause and the following clauses, leaving `end` shared by them.
+func (p *Parser) parseIfClauses() (*ast.IfStmt, error) {
+	start := p.curToken.Pos
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
@@ -324,7 +497,24 @@ func (p *Parser) parseIfStmt() (*ast.IfStmt, error) {
 		return nil, err
 	}
 	var elseBody []ast.Stmt
-	if p.curToken.Type == lexer.TElse {
+	switch {
+	case p.curToken.Type == lexer.TElif:
+		elif, err := p.parseIfClauses()
+		if err != nil {
+			return nil, err
+		}
+		elseBody = []ast.Stmt{elif}
+	case p.curToken.Type == lexer.TElse && p.peekToken.Type == lexer.TIf:
+		// `else if` is the same as `elif`
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		elif, err := p.parseIfClauses()
+		if err != nil {
+			return nil, err
+		}
+		elseBody = []ast.Stmt{elif}
+	case p.curToken.Type == lexer.TElse:
 		if err := p.readToken(); err != nil {
 			return nil, err
 		}
@@ -333,17 +523,143 @@ func (p *Parser) parseIfStmt() (*ast.IfStmt, error) {
 			return nil, err
 		}
 	}
-	if err := p.expect(lexer.TEnd); err != nil {
-		return nil, err
-	}
 	return &ast.IfStmt{
+		Pos:  p.span(start),
 		Cond: cond,
 		Then: thenBody,
 		Else: elseBody,
 	}, nil
 }
 
+// parseTryStmt parses `try ... catch err ... finally ... end`, where either clause can be omitted, but not both.
+func (p *Parser) parseTryStmt() (*ast.TryStmt, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &ast.TryStmt{Body: body}
+	if p.curToken.Type == lexer.TCatch {
+		if err := p.expectNext(lexer.TIdent); err != nil {
+			return nil, err
+		}
+		stmt.Name = p.curToken.Text
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		stmt.Catch, err = p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	hasFinally := p.curToken.Type == lexer.TFinally
+	if hasFinally {
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		stmt.Finally, err = p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Name == "" && !hasFinally {
+		return nil, p.errorf(p.curToken.Pos, "try without catch or finally")
+	}
+	if err := p.expect(lexer.TEnd); err != nil {
+		return nil, err
+	}
+	stmt.Pos = p.span(start)
+	return stmt, nil
+}
+
+func (p *Parser) parseThrowStmt() (*ast.ThrowStmt, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	value, err := p.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ThrowStmt{
+		Pos:   p.span(start),
+		Value: value,
+	}, nil
+}
+
+func (p *Parser) parseMatchStmt() (*ast.MatchStmt, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	subject, err := p.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+	var cases []*ast.MatchCase
+	for p.curToken.Type == lexer.TCase {
+		c, err := p.parseMatchCase()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	var elseBody []ast.Stmt
+	if p.curToken.Type == lexer.TElse {
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		elseBody, err = p.parseBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(lexer.TEnd); err != nil {
+		return nil, err
+	}
+	return &ast.MatchStmt{
+		Pos:     p.span(start),
+		Subject: subject,
+		Cases:   cases,
+		Else:    elseBody,
+	}, nil
+}
+
+func (p *Parser) parseMatchCase() (*ast.MatchCase, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	var patterns []ast.Expr
+	for {
+		pattern, err := p.parseExpr(PLowest)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+		if p.curToken.Type != lexer.TComma {
+			break
+		}
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+	}
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.MatchCase{
+		Pos:      p.span(start),
+		Patterns: patterns,
+		Body:     body,
+	}, nil
+}
+
 func (p *Parser) parseFunLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	var name string
 	if p.peekToken.Type == lexer.TIdent {
 		if err := p.readToken(); err != nil {
@@ -371,6 +687,7 @@ func (p *Parser) parseFunLiteralExpr() (ast.Expr, error) {
 	}
 
 	return &ast.FunLiteralExpr{
+		Pos:  p.span(start),
 		Name: name,
 		Args: args,
 		Body: body,
@@ -381,7 +698,7 @@ func (p *Parser) parseFunLiteralArgs() ([]string, error) {
 	var args []string
 	for {
 		if p.peekToken.Type == lexer.TEOF {
-			return nil, fmt.Errorf("unexpected eof while reading function arguments")
+			return nil, p.errorf(p.peekToken.Pos, "unexpected eof while reading function arguments")
 		}
 		if p.peekToken.Type == lexer.TRParen {
 			break
@@ -410,7 +727,7 @@ func (p *Parser) parseFunLiteralArgs() ([]string, error) {
 func (p *Parser) parseExpr(precedence Precedence) (expr ast.Expr, err error) {
 	prefix, ok := p.prefixParsers[p.curToken.Type]
 	if !ok {
-		return nil, fmt.Errorf("no prefix parser found for %s", p.curToken.Type)
+		return nil, p.errorf(p.curToken.Pos, "no prefix parser found for %s", p.curToken.Type)
 	}
 	expr, err = prefix()
 	if err != nil {
@@ -435,6 +752,7 @@ func (p *Parser) parseExpr(precedence Precedence) (expr ast.Expr, err error) {
 }
 
 func (p *Parser) parsePrefixExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	op := p.curToken.Text
 	if err := p.readToken(); err != nil {
 		return nil, err
@@ -444,21 +762,75 @@ func (p *Parser) parsePrefixExpr() (ast.Expr, error) {
 		return nil, err
 	}
 	return &ast.PrefixExpr{
+		Pos:   p.span(start),
 		Op:    op,
 		Right: right,
 	}, nil
 }
 
+// parseNotExpr parses `not` binding looser than comparisons, i.e. `not a == b` is `not (a == b)`.
+func (p *Parser) parseNotExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseExpr(PNot)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.PrefixExpr{
+		Pos:   p.span(start),
+		Op:    "not",
+		Right: right,
+	}, nil
+}
+
+func (p *Parser) parseGroupedExpr() (ast.Expr, error) {
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(lexer.TRParen); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
 func (p *Parser) parseInfixExpr(left ast.Expr) (ast.Expr, error) {
+	start := left.Span()
 	op := p.curToken.Text
+	precedence := precedenceOf(p.curToken.Type)
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
-	right, err := p.parseExpr(PPrefix)
+	right, err := p.parseExpr(precedence)
 	if err != nil {
 		return nil, err
 	}
 	return &ast.InfixExpr{
+		Pos:   p.span(start),
+		Op:    op,
+		Left:  left,
+		Right: right,
+	}, nil
+}
+
+func (p *Parser) parseLogicalExpr(left ast.Expr) (ast.Expr, error) {
+	start := left.Span()
+	op := p.curToken.Text
+	precedence := precedenceOf(p.curToken.Type)
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseExpr(precedence)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.LogicalExpr{
+		Pos:   p.span(start),
 		Op:    op,
 		Left:  left,
 		Right: right,
@@ -466,33 +838,42 @@ func (p *Parser) parseInfixExpr(left ast.Expr) (ast.Expr, error) {
 }
 
 func (p *Parser) parseFieldAccessExpr(record ast.Expr) (ast.Expr, error) {
-	// current token is TDot
+	start := record.Span()
+	// current token is TDot or TQuestionDot
+	optional := p.curToken.Type == lexer.TQuestionDot
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
 	// next token should be an identifier
 	if p.curToken.Type != lexer.TIdent {
-		return nil, fmt.Errorf("expected identifier after '.', got %s", p.curToken.Type)
+		return nil, p.errorf(p.curToken.Pos, "expected identifier after '.', got %s", p.curToken.Type)
 	}
 	fieldName := p.curToken.Text
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
 	return &ast.FieldAccessExpr{
-		Record: record,
-		Field:  fieldName,
+		Pos:      p.span(start),
+		Record:   record,
+		Field:    fieldName,
+		Optional: optional,
 	}, nil
 }
 
 func (p *Parser) parseVarRefExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	name := p.curToken.Text
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
-	return &ast.VarRefExpr{Name: name}, nil
+	return &ast.VarRefExpr{
+		Pos:  p.span(start),
+		Name: name,
+	}, nil
 }
 
 func (p *Parser) parseFunCallExpr(fun ast.Expr) (ast.Expr, error) {
+	start := fun.Span()
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
@@ -501,6 +882,7 @@ func (p *Parser) parseFunCallExpr(fun ast.Expr) (ast.Expr, error) {
 		return nil, err
 	}
 	return &ast.FunCallExpr{
+		Pos:  p.span(start),
 		Fun:  fun,
 		Args: args,
 	}, nil
@@ -510,7 +892,7 @@ func (p *Parser) parseFunCallArgs() ([]ast.Expr, error) {
 	var args []ast.Expr
 	for {
 		if p.curToken.Type == lexer.TEOF {
-			return nil, fmt.Errorf("unexpected token while reading arguments for function call")
+			return nil, p.errorf(p.curToken.Pos, "unexpected token while reading arguments for function call")
 		}
 		if p.curToken.Type == lexer.TRParen {
 			break
@@ -534,6 +916,7 @@ func (p *Parser) parseFunCallArgs() ([]ast.Expr, error) {
 }
 
 func (p *Parser) parseIndexOrSliceExpr(left ast.Expr) (ast.Expr, error) {
+	leftPos := left.Span()
 	// current token is TLBrace ('['
 	if err := p.readToken(); err != nil {
 		return nil, err
@@ -549,7 +932,7 @@ func (p *Parser) parseIndexOrSliceExpr(left ast.Expr) (ast.Expr, error) {
 		// start is nil
 	} else if p.curToken.Type == lexer.TRBrace {
 		// Empty brackets [] - error
-		return nil, fmt.Errorf("expected index or slice expression")
+		return nil, p.errorf(p.curToken.Pos, "expected index or slice expression")
 	} else {
 		// Parse the first expression
 		expr, err := p.parseExpr(PLowest)
@@ -563,12 +946,13 @@ func (p *Parser) parseIndexOrSliceExpr(left ast.Expr) (ast.Expr, error) {
 		} else {
 			// It's an index access
 			if p.curToken.Type != lexer.TRBrace {
-				return nil, fmt.Errorf("expected ']' after index, got %s", p.curToken.Type)
+				return nil, p.errorf(p.curToken.Pos, "expected ']' after index, got %s", p.curToken.Type)
 			}
 			if err := p.readToken(); err != nil {
 				return nil, err
 			}
 			return &ast.IndexExpr{
+				Pos:   p.span(leftPos),
 				Left:  left,
 				Index: expr,
 			}, nil
@@ -588,53 +972,160 @@ func (p *Parser) parseIndexOrSliceExpr(left ast.Expr) (ast.Expr, error) {
 	}
 
 	if p.curToken.Type != lexer.TRBrace {
-		return nil, fmt.Errorf("expected ']' after slice, got %s", p.curToken.Type)
+		return nil, p.errorf(p.curToken.Pos, "expected ']' after slice, got %s", p.curToken.Type)
 	}
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
 
 	return &ast.SliceExpr{
+		Pos:   p.span(leftPos),
 		Left:  left,
 		Start: start,
 		End:   end,
 	}, nil
 }
 
+// parseDigitLiteralExpr parses an integer, e.g. `1_000` or `0xff`, or a number with a fraction.
 func (p *Parser) parseDigitLiteralExpr() (ast.Expr, error) {
-	value, err := strconv.ParseFloat(p.curToken.Text, 64)
+	start := p.curToken.Pos
+	text := p.curToken.Text
+	if strings.ContainsRune(text, '.') {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, p.errorf(p.curToken.Pos, "invalid number literal '%s'", text)
+		}
+		if err := p.readToken(); err != nil {
+			return nil, err
+		}
+		return &ast.NumberLiteralExpr{
+			Pos:   p.span(start),
+			Value: value,
+		}, nil
+	}
+	value, err := parseInt(text)
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, p.errorf(p.curToken.Pos, "integer literal '%s' is out of range", text)
+	}
 	if err != nil {
-		return nil, err
+		return nil, p.errorf(p.curToken.Pos, "invalid number literal '%s'", text)
 	}
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
-	return &ast.NumberLiteralExpr{
+	return &ast.IntLiteralExpr{
+		Pos:   p.span(start),
 		Value: value,
 	}, nil
 }
 
+// parseInt parses an integer literal, which is decimal unless prefixed by 0x, 0b or 0o.
+func parseInt(text string) (int64, error) {
+	if len(text) < 2 || !strings.ContainsRune("xXbBoO", rune(text[1])) {
+		// base 0 would read a leading zero as octal, e.g. 010
+		text = strings.TrimLeft(text, "0")
+		if text == "" {
+			return 0, nil
+		}
+		if text[0] == '_' {
+			return 0, strconv.ErrSyntax
+		}
+	}
+	return strconv.ParseInt(text, 0, 64)
+}
+
 func (p *Parser) parseBoolLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	value := p.curToken.Type == lexer.TTrue
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
 	return &ast.BoolLiteralExpr{
+		Pos:   p.span(start),
 		Value: value,
 	}, nil
 }
 
+func (p *Parser) parseNilLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	return &ast.NilLiteralExpr{Pos: p.span(start)}, nil
+}
+
 func (p *Parser) parseStringLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	value := p.curToken.Text
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
 	return &ast.StringLiteralExpr{
+		Pos:   p.span(start),
 		Value: value,
 	}, nil
 }
 
+func (p *Parser) parseInterpolatedStringLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
+	texts, spans, err := lexer.SplitInterpolated(p.src.Text, p.curToken.Pos)
+	if err != nil {
+		return nil, p.errorf(p.curToken.Pos, "%s", err)
+	}
+	if err := p.readToken(); err != nil {
+		return nil, err
+	}
+	if len(spans) == 0 {
+		return &ast.StringLiteralExpr{
+			Pos:   p.span(start),
+			Value: texts[0],
+		}, nil
+	}
+	var values []ast.Expr
+	for _, span := range spans {
+		value, err := p.parseEmbeddedExpr(span)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return &ast.InterpolatedStringLiteralExpr{
+		Pos:    p.span(start),
+		Texts:  texts,
+		Values: values,
+	}, nil
+}
+
+// parseEmbeddedExpr parses the expression at span of the source,
+// e.g. `{name}` segments of interpolated strings.
+func (p *Parser) parseEmbeddedExpr(span lexer.Pos) (ast.Expr, error) {
+	sub := &Parser{
+		src: p.src,
+		lex: lexer.NewAt(p.src.Text[:span.End], span.Start),
+	}
+	sub.registerPrefixParsers()
+	sub.registerInfixParsers()
+	if err := sub.readToken(); err != nil {
+		return nil, err
+	}
+	if err := sub.readToken(); err != nil {
+		return nil, err
+	}
+	if sub.curToken.Type == lexer.TEOF {
+		return nil, p.errorf(span, "empty expression in string literal")
+	}
+	expr, err := sub.parseExpr(PLowest)
+	if err != nil {
+		return nil, err
+	}
+	if sub.curToken.Type != lexer.TEOF {
+		return nil, sub.errorf(sub.curToken.Pos, "unexpected %s in string literal", sub.curToken.Type)
+	}
+	return expr, nil
+}
+
 func (p *Parser) parseListLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
@@ -642,7 +1133,7 @@ func (p *Parser) parseListLiteralExpr() (ast.Expr, error) {
 	var elements []ast.Expr
 	for {
 		if p.curToken.Type == lexer.TEOF {
-			return nil, fmt.Errorf("unexpected EOF while reading list")
+			return nil, p.errorf(p.curToken.Pos, "unexpected EOF while reading list")
 		}
 		if p.curToken.Type == lexer.TRBrace {
 			break
@@ -650,6 +1141,7 @@ func (p *Parser) parseListLiteralExpr() (ast.Expr, error) {
 
 		// Check for spread expression
 		if p.curToken.Type == lexer.TEllipsis {
+			spreadStart := p.curToken.Pos
 			if err := p.readToken(); err != nil {
 				return nil, err
 			}
@@ -657,7 +1149,7 @@ func (p *Parser) parseListLiteralExpr() (ast.Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			elements = append(elements, &ast.SpreadExpr{Expr: expr})
+			elements = append(elements, &ast.SpreadExpr{Pos: p.span(spreadStart), Expr: expr})
 		} else {
 			elem, err := p.parseExpr(PLowest)
 			if err != nil {
@@ -680,11 +1172,13 @@ func (p *Parser) parseListLiteralExpr() (ast.Expr, error) {
 	}
 
 	return &ast.ListLiteralExpr{
+		Pos:      p.span(start),
 		Elements: elements,
 	}, nil
 }
 
 func (p *Parser) parseRecordLiteralExpr() (ast.Expr, error) {
+	start := p.curToken.Pos
 	// current token is TLBracket ("{")
 	if err := p.readToken(); err != nil {
 		return nil, err
@@ -695,7 +1189,7 @@ func (p *Parser) parseRecordLiteralExpr() (ast.Expr, error) {
 		if err := p.readToken(); err != nil {
 			return nil, err
 		}
-		return &ast.RecordLiteralExpr{Elements: elements}, nil
+		return &ast.RecordLiteralExpr{Pos: p.span(start), Elements: elements}, nil
 	}
 	for {
 		// Check for spread expression
@@ -723,7 +1217,7 @@ func (p *Parser) parseRecordLiteralExpr() (ast.Expr, error) {
 			}
 			elements = append(elements, &ast.RecordField{Key: name, Value: expr})
 		} else {
-			return nil, fmt.Errorf("expected identifier or spread (...) for record field, got %s", p.curToken.Type)
+			return nil, p.errorf(p.curToken.Pos, "expected identifier or spread (...) for record field, got %s", p.curToken.Type)
 		}
 
 		if p.curToken.Type == lexer.TRBracket {
@@ -742,10 +1236,10 @@ func (p *Parser) parseRecordLiteralExpr() (ast.Expr, error) {
 			// otherwise continue to next field
 			continue
 		}
-		return nil, fmt.Errorf("expected comma or '}', got %s", p.curToken.Type)
+		return nil, p.errorf(p.curToken.Pos, "expected comma or '}', got %s", p.curToken.Type)
 	}
 	if err := p.readToken(); err != nil {
 		return nil, err
 	}
-	return &ast.RecordLiteralExpr{Elements: elements}, nil
+	return &ast.RecordLiteralExpr{Pos: p.span(start), Elements: elements}, nil
 }
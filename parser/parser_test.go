@@ -48,3 +48,107 @@ func TestParseWhile(t *testing.T) {
 		t.Fatalf("expected WhileStmt, got %T", v[0])
 	}
 }
+
+func TestParseFor(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"for x in xs end", `ForStmt{"", "x", VarRefExpr{"xs"}, }`},
+		{"for i, x in f(xs) y = x end", `ForStmt{"i", "x", FunCallExpr{VarRefExpr{"f"}, [VarRefExpr{"xs"}]}, VarDeclStmt{"y", VarRefExpr{"x"}}}`},
+	}
+	for _, tt := range tests {
+		v, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v) != 1 {
+			t.Fatalf("expected 1 stmt, got %d", len(v))
+		}
+		if actual := v[0].Inspect(); actual != tt.expected {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseForError(t *testing.T) {
+	for _, text := range []string{"for in xs end", "for x xs end", "for x, in xs end", "for x in xs"} {
+		if _, err := Parse([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
+
+func TestParseElif(t *testing.T) {
+	expected := `IfStmt{VarRefExpr{"a"}, VarDeclStmt{"x", IntLiteralExpr{1}}, IfStmt{VarRefExpr{"b"}, VarDeclStmt{"x", IntLiteralExpr{2}}, VarDeclStmt{"x", IntLiteralExpr{3}}}}`
+	for _, text := range []string{
+		"if a x = 1 elif b x = 2 else x = 3 end",
+		"if a x = 1 else if b x = 2 else x = 3 end",
+	} {
+		v, err := Parse([]rune(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v) != 1 {
+			t.Fatalf("expected 1 stmt, got %d", len(v))
+		}
+		if actual := v[0].Inspect(); actual != expected {
+			t.Fatalf("%s: expected %s, but got %s", text, expected, actual)
+		}
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	text := "match x case 1, 'a' y = 1 case [a, ...b] case { k = v } else y = 2 end"
+	v, err := Parse([]rune(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := v[0].(*ast.MatchStmt)
+	if !ok {
+		t.Fatalf("expected MatchStmt, got %T", v[0])
+	}
+	if len(m.Cases) != 3 || len(m.Cases[0].Patterns) != 2 || len(m.Cases[0].Body) != 1 || len(m.Else) != 1 {
+		t.Fatalf("unexpected match: %s", m.Inspect())
+	}
+}
+
+func TestParseMatchError(t *testing.T) {
+	for _, text := range []string{"match x case end", "match x y = 1 end", "match x case 1"} {
+		if _, err := Parse([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
+
+func TestParseTry(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"try f() catch e g(e) end", `TryStmt{[FunCallExpr{VarRefExpr{"f"}, []}], "e", [FunCallExpr{VarRefExpr{"g"}, [VarRefExpr{"e"}]}], []}`},
+		{"try f() finally g() end", `TryStmt{[FunCallExpr{VarRefExpr{"f"}, []}], "", [], [FunCallExpr{VarRefExpr{"g"}, []}]}`},
+		{"try catch e finally end", `TryStmt{[], "e", [], []}`},
+		{"throw 'x'", `ThrowStmt{StringLiteralExpr{x}}`},
+	}
+	for _, tt := range tests {
+		v, err := Parse([]rune(tt.text))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if len(v) != 1 {
+			t.Fatalf("expected 1 stmt, got %d", len(v))
+		}
+		if actual := v[0].Inspect(); actual != tt.expected {
+			t.Fatalf("%s: expected %s, but got %s", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestParseTryError(t *testing.T) {
+	for _, text := range []string{"try f() end", "try f() catch g() end", "try f() catch e", "try f() finally g()", "throw"} {
+		if _, err := Parse([]rune(text)); err == nil {
+			t.Fatalf("%s: expected error", text)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fj68/vvlang/ast"
+	"github.com/fj68/vvlang/lexer"
+)
+
+func TestParseSpans(t *testing.T) {
+	text := "x = a + f(1)\nwhile x < 3 x = x + 1 end"
+	program, err := Parse([]rune(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := program[0].(*ast.VarDeclStmt)
+	if !decl.Span().Eq(lexer.Pos{Start: 0, End: 12}) {
+		t.Fatalf("unexpected span of VarDeclStmt: %s", decl.Span())
+	}
+	infix := decl.Body.(*ast.InfixExpr)
+	if !infix.Span().Eq(lexer.Pos{Start: 4, End: 12}) {
+		t.Fatalf("unexpected span of InfixExpr: %s", infix.Span())
+	}
+	call := infix.Right.(*ast.FunCallExpr)
+	if !call.Span().Eq(lexer.Pos{Start: 8, End: 12}) {
+		t.Fatalf("unexpected span of FunCallExpr: %s", call.Span())
+	}
+	while := program[1].(*ast.WhileStmt)
+	if !while.Span().Eq(lexer.Pos{Start: 13, End: 38}) {
+		t.Fatalf("unexpected span of WhileStmt: %s", while.Span())
+	}
+	if !while.Cond.Span().Eq(lexer.Pos{Start: 19, End: 24}) {
+		t.Fatalf("unexpected span of cond: %s", while.Cond.Span())
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	src := lexer.NewSource("test.vv", []rune("x = 1\ny = )x\n"))
+	_, err := ParseSource(src)
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *SyntaxError, got %T", err)
+	}
+	expected := "test.vv:2:5: no prefix parser found for RParen\ny = )x\n    ^"
+	if serr.Error() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, serr.Error())
+	}
+}
+
+func TestSyntaxErrorFromLexer(t *testing.T) {
+	src := lexer.NewSource("test.vv", []rune("x = 'abc"))
+	_, err := ParseSource(src)
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected *SyntaxError, got %T", err)
+	}
+	if line, _ := src.LineCol(serr.Pos.Start); line != 1 {
+		t.Fatalf("expected error on line 1, got %d", line)
+	}
+}
@@ -0,0 +1,274 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fj68/vvlang/ast"
+	"github.com/fj68/vvlang/interp"
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/parser"
+)
+
+const (
+	Prompt         = "> "
+	ContinuePrompt = "... "
+)
+
+// REPL reads inputs line by line and evaluates them on a single State,
+// so that variables and functions defined by an input are kept for the next ones.
+type REPL struct {
+	State *interp.State
+	// NewState creates the State to start with and to replace on `:reset`.
+	NewState func() *interp.State
+	// HistoryFile is the path history is loaded from and saved to.
+	// History is kept only in memory if it is empty.
+	HistoryFile string
+	History     []string
+
+	in       *bufio.Scanner
+	out      io.Writer
+	lastAST  []ast.Stmt
+	quitting bool
+}
+
+func New(in io.Reader, out io.Writer, newState func() *interp.State) *REPL {
+	return &REPL{
+		State:    newState(),
+		NewState: newState,
+		in:       bufio.NewScanner(in),
+		out:      out,
+	}
+}
+
+// Run reads and evaluates inputs until EOF or `:quit`.
+// Failing to load or save history does not stop the session, but keeps history only in memory.
+func (r *REPL) Run() error {
+	if err := r.loadHistory(); err != nil {
+		r.disableHistoryFile(err)
+	}
+	for !r.quitting {
+		input, ok := r.read()
+		if !ok {
+			break
+		}
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(input), "!") {
+			entry, err := r.recall(strings.TrimSpace(input))
+			if err != nil {
+				fmt.Fprintln(r.out, err)
+				continue
+			}
+			// show what is run again, which is saved instead of `!N`
+			fmt.Fprintln(r.out, entry)
+			input = entry
+		}
+		if err := r.addHistory(input); err != nil {
+			r.disableHistoryFile(err)
+		}
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			r.command(strings.TrimSpace(input))
+			continue
+		}
+		r.eval(input)
+	}
+	return r.in.Err()
+}
+
+// read reads lines until they make a complete input.
+func (r *REPL) read() (string, bool) {
+	var lines []string
+	prompt := Prompt
+	for {
+		fmt.Fprint(r.out, prompt)
+		if !r.in.Scan() {
+			if len(lines) == 0 {
+				fmt.Fprintln(r.out)
+				return "", false
+			}
+			// let the parser report the unterminated block
+			return strings.Join(lines, "\n"), true
+		}
+		lines = append(lines, r.in.Text())
+		input := strings.Join(lines, "\n")
+		// commands and references to history are single lines
+		trimmed := strings.TrimSpace(input)
+		if strings.HasPrefix(trimmed, ":") || strings.HasPrefix(trimmed, "!") || !incomplete(input) {
+			return input, true
+		}
+		prompt = ContinuePrompt
+	}
+}
+
+// incomplete reports whether input has blocks which are not closed by `end` yet.
+func incomplete(input string) bool {
+	lex := lexer.New([]rune(input))
+	depth := 0
+	prev := lexer.TEOF
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			// leave lexical errors to the parser
+			return false
+		}
+		switch tok.Type {
+		case lexer.TEOF:
+			return 0 < depth
+		case lexer.TIf:
+			// `else if` continues the block of the `if`
+			if prev != lexer.TElse {
+				depth++
+			}
+		case lexer.TFun, lexer.TWhile, lexer.TFor, lexer.TMatch, lexer.TTry:
+			depth++
+		case lexer.TEnd:
+			depth--
+		}
+		prev = tok.Type
+	}
+}
+
+func (r *REPL) eval(input string) {
+	src := lexer.NewSource("", []rune(input))
+	program, err := parser.ParseSource(src)
+	if err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+	r.lastAST = program
+
+	// evaluate a trailing bare expression as a return value to print it
+	if len(program) != 0 {
+		if stmt, ok := program[len(program)-1].(*ast.ExprStmt); ok && !isFunDecl(stmt.Expr) {
+			program = append(program[:len(program)-1:len(program)-1], &ast.ReturnStmt{Pos: stmt.Span(), Value: stmt.Expr})
+		}
+	}
+
+	n := r.State.RetVals.Len()
+	if err := r.State.EvalProgram(src, program); err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+	for n < r.State.RetVals.Len() {
+		if v := r.State.RetVals.Pop(); v != nil {
+			fmt.Fprintln(r.out, v)
+		}
+	}
+}
+
+func isFunDecl(expr ast.Expr) bool {
+	f, ok := expr.(*ast.FunLiteralExpr)
+	return ok && f.Name != ""
+}
+
+func (r *REPL) command(input string) {
+	name, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case ":env":
+		fmt.Fprint(r.out, r.State.Env.String())
+	case ":ast":
+		r.showAST(arg)
+	case ":reset":
+		r.State = r.NewState()
+		r.lastAST = nil
+	case ":history":
+		for i, entry := range r.History {
+			fmt.Fprintf(r.out, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+	case ":help":
+		fmt.Fprint(r.out, help)
+	case ":quit":
+		r.quitting = true
+	default:
+		fmt.Fprintf(r.out, "unknown command '%s', type :help to list commands\n", name)
+	}
+}
+
+const help = `:env          show variables
+:ast [code]   show syntax tree of code, or of the last input
+:reset        discard all variables
+:history      show inputs entered so far
+!N            run the N-th input of :history again
+!!            run the last input again
+:help         show this message
+:quit         exit
+`
+
+func (r *REPL) showAST(code string) {
+	program := r.lastAST
+	if code != "" {
+		var err error
+		program, err = parser.Parse([]rune(code))
+		if err != nil {
+			fmt.Fprintln(r.out, err)
+			return
+		}
+	}
+	for _, stmt := range program {
+		fmt.Fprintln(r.out, stmt.Inspect())
+	}
+}
+
+// loadHistory reads history saved by previous sessions.
+// Each entry is saved as a quoted string per line, so that multi-line inputs are kept as one.
+func (r *REPL) loadHistory() error {
+	if r.HistoryFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.HistoryFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, err := strconv.Unquote(line); err == nil {
+			r.History = append(r.History, entry)
+		}
+	}
+	return nil
+}
+
+// recall returns the entry of history referred by ref, i.e. `!N` for the N-th entry or `!!` for the last one.
+func (r *REPL) recall(ref string) (string, error) {
+	n := len(r.History)
+	if ref != "!!" {
+		var err error
+		n, err = strconv.Atoi(ref[1:])
+		if err != nil {
+			return "", fmt.Errorf("unknown history reference '%s', type :history to list entries", ref)
+		}
+	}
+	if n < 1 || len(r.History) < n {
+		return "", fmt.Errorf("history entry '%s' is not found, type :history to list entries", ref)
+	}
+	return r.History[n-1], nil
+}
+
+// disableHistoryFile reports err of HistoryFile once, and stops using it.
+func (r *REPL) disableHistoryFile(err error) {
+	fmt.Fprintf(r.out, "history is kept only in memory: %s\n", err)
+	r.HistoryFile = ""
+}
+
+func (r *REPL) addHistory(input string) error {
+	r.History = append(r.History, input)
+	if r.HistoryFile == "" {
+		return nil
+	}
+	f, err := os.OpenFile(r.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, strconv.Quote(input))
+	return err
+}
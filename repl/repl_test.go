@@ -0,0 +1,178 @@
+package repl
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fj68/vvlang/interp"
+)
+
+func newState() *interp.State {
+	s := interp.NewState()
+	s.RegisterGlobals(interp.DefaultBuiltins)
+	return s
+}
+
+func run(t *testing.T, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	r := New(strings.NewReader(input), &out, newState)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestREPLKeepsState(t *testing.T) {
+	out := run(t, "x = 1\nx + 1\n")
+	expected := "> > 2\n> \n"
+	if out != expected {
+		t.Fatalf("expected %q, but got %q", expected, out)
+	}
+}
+
+func TestREPLMultiLine(t *testing.T) {
+	out := run(t, "fun twice(a)\n  if a < 0\n    return 0\n  end\n  return a * 2\nend\ntwice(4)\n")
+	expected := "> ... ... ... ... ... > 8\n> \n"
+	if out != expected {
+		t.Fatalf("expected %q, but got %q", expected, out)
+	}
+}
+
+func TestREPLError(t *testing.T) {
+	out := run(t, "y\n1\n")
+	if !strings.Contains(out, "variable named 'y' is not found") {
+		t.Fatalf("expected error in output, but got %q", out)
+	}
+	if !strings.HasSuffix(out, "> 1\n> \n") {
+		t.Fatalf("expected REPL to continue after error, but got %q", out)
+	}
+}
+
+func TestREPLReset(t *testing.T) {
+	out := run(t, "x = 1\n:reset\nx\n")
+	if !strings.Contains(out, "variable named 'x' is not found") {
+		t.Fatalf("expected x to be discarded, but got %q", out)
+	}
+}
+
+func TestREPLEnv(t *testing.T) {
+	out := run(t, "answer = 42\n:env\n")
+	if !strings.Contains(out, "answer = 42\n") {
+		t.Fatalf("expected env to contain answer, but got %q", out)
+	}
+}
+
+func TestREPLAST(t *testing.T) {
+	out := run(t, "x = 1\n:ast\n:ast 1 + 2\n")
+	expected := "> > VarDeclStmt{\"x\", IntLiteralExpr{1}}\n> InfixExpr{\"+\", IntLiteralExpr{1}, IntLiteralExpr{2}}\n> \n"
+	if out != expected {
+		t.Fatalf("expected %q, but got %q", expected, out)
+	}
+}
+
+func TestREPLHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	r := New(strings.NewReader("x = 1\nfun f()\n  return x\nend\n"), &bytes.Buffer{}, newState)
+	r.HistoryFile = path
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	r = New(strings.NewReader(""), &bytes.Buffer{}, newState)
+	r.HistoryFile = path
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"x = 1", "fun f()\n  return x\nend"}
+	if len(r.History) != len(expected) {
+		t.Fatalf("expected %q, but got %q", expected, r.History)
+	}
+	for i, entry := range expected {
+		if r.History[i] != entry {
+			t.Fatalf("expected %q, but got %q", entry, r.History[i])
+		}
+	}
+}
+
+func TestREPLHistoryWriteError(t *testing.T) {
+	// the directory of the history file does not exist
+	path := filepath.Join(t.TempDir(), "missing", "history")
+
+	var out bytes.Buffer
+	r := New(strings.NewReader("x = 1\ny = 2\n:env\n"), &out, newState)
+	r.HistoryFile = path
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out.String(), "history is kept only in memory"); n != 1 {
+		t.Fatalf("expected the error reported once, but got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "y = 2\n") {
+		t.Fatalf("expected the session to go on, but got %q", out.String())
+	}
+	if len(r.History) != 3 {
+		t.Fatalf("expected 3 entries, but got %q", r.History)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"x = 1", false},
+		{"fun f()", true},
+		{"fun f() return 1 end", false},
+		{"while true\n  if x", true},
+		{"while true\n  if x\n  end", true},
+		{"while true\n  if x\n  end\nend", false},
+		{"f = fun(x)", true},
+		{"for x in xs", true},
+		{"for k, v in r print(k) end", false},
+		{"if a\n  x = 1\nelse if b\n  x = 2\nend", false},
+		{"if a\n  x = 1\nelif b\n  x = 2", true},
+		{"match x\ncase 1\n  y = 1", true},
+		{"match x\ncase 1\n  y = 1\nelse\n  y = 2\nend", false},
+		{"try\n  f()\ncatch err", true},
+		{"try\n  f()\ncatch err\n  print(err)\nfinally\n  g()\nend", false},
+		{"'end", false},
+	}
+	for _, tt := range tests {
+		if actual := incomplete(tt.input); actual != tt.expected {
+			t.Fatalf("%q: expected %t, but got %t", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestREPLRecall(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 1\nx + 1\n!2\n", "> > 2\n> x + 1\n2\n> \n"},
+		{"x = 1\nx = x + 1\n!!\nx\n", "> > > x = x + 1\n> 3\n> \n"},
+		{"fun f()\n  return 4\nend\n!1\nf()\n", "> ... ... > fun f()\n  return 4\nend\n> 4\n> \n"},
+		{":help\n!!\n", "> " + help + "> :help\n" + help + "> \n"},
+		{"1\n!3\n", "> 1\n> history entry '!3' is not found, type :history to list entries\n> \n"},
+		{"!!\n", "> history entry '!!' is not found, type :history to list entries\n> \n"},
+		{"!x\n", "> unknown history reference '!x', type :history to list entries\n> \n"},
+	}
+	for _, tt := range tests {
+		if out := run(t, tt.input); out != tt.expected {
+			t.Fatalf("%q: expected %q, but got %q", tt.input, tt.expected, out)
+		}
+	}
+
+	// entries run again are saved as they are, not as references
+	r := New(strings.NewReader("x = 1\n!1\n"), &bytes.Buffer{}, newState)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.History) != 2 || r.History[1] != "x = 1" {
+		t.Fatalf("expected the entry to be saved again, but got %q", r.History)
+	}
+}
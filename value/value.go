@@ -0,0 +1,491 @@
+package value
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValueType is the type of values, including functions and errors,
+// which are defined by the vm and interp packages as they are run by the VM.
+type ValueType int
+
+const (
+	VTBool ValueType = iota
+	VTNumber
+	VTString
+	VTUserFun
+	VTBuiltinFun
+	VTList
+	VTRecord
+	VTRange
+	VTNil
+	VTInt
+	VTError
+)
+
+func (ty ValueType) String() string {
+	switch ty {
+	case VTBool:
+		return "bool"
+	case VTNumber:
+		return "number"
+	case VTString:
+		return "string"
+	case VTUserFun:
+		return "fun"
+	case VTBuiltinFun:
+		return "fun"
+	case VTList:
+		return "list"
+	case VTRecord:
+		return "record"
+	case VTRange:
+		return "range"
+	case VTNil:
+		return "nil"
+	case VTInt:
+		return "int"
+	case VTError:
+		return "error"
+	}
+	return "unknown"
+}
+
+type Value interface {
+	Type() ValueType
+	String() string
+	Equal(Value) (bool, error)
+	LessThan(Value) (bool, error)
+}
+
+// VNil is the absence of a value, e.g. the result of a function without `return`.
+type VNil struct{}
+
+func (v VNil) Type() ValueType {
+	return VTNil
+}
+
+func (v VNil) String() string {
+	return "nil"
+}
+
+// Equal reports whether other is nil, which is comparable with any value.
+func (v VNil) Equal(other Value) (bool, error) {
+	_, ok := other.(VNil)
+	return ok, nil
+}
+
+func (v VNil) LessThan(other Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare nil")
+}
+
+type VBool bool
+
+func (v VBool) Type() ValueType {
+	return VTBool
+}
+
+func (v VBool) String() string {
+	return fmt.Sprintf("%t", bool(v))
+}
+
+func (v VBool) Equal(other Value) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	x, ok := other.(VBool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, but got %s", other.Type())
+	}
+	return bool(x) == bool(v), nil
+}
+
+func (v VBool) LessThan(other Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare bool")
+}
+
+// VInt is an integer, e.g. `1_000` or `0xff`, which is promoted to VNumber when mixed with it.
+type VInt int64
+
+func (v VInt) Type() ValueType {
+	return VTInt
+}
+
+func (v VInt) String() string {
+	return strconv.FormatInt(int64(v), 10)
+}
+
+func (v VInt) Equal(other Value) (bool, error) {
+	if x, ok := other.(VInt); ok {
+		return x == v, nil
+	}
+	return VNumber(v).Equal(other)
+}
+
+func (v VInt) LessThan(other Value) (bool, error) {
+	if x, ok := other.(VInt); ok {
+		return v < x, nil
+	}
+	return VNumber(v).LessThan(other)
+}
+
+// VNumber is a floating point number, e.g. `0.5`.
+type VNumber float64
+
+func (v VNumber) Type() ValueType {
+	return VTNumber
+}
+
+func (v VNumber) String() string {
+	return fmt.Sprintf("%g", float64(v))
+}
+
+func (v VNumber) Equal(other Value) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	x, ok := ToFloat(other)
+	if !ok {
+		return false, fmt.Errorf("expected number, but got %s", other.Type())
+	}
+	return x == float64(v), nil
+}
+
+func (v VNumber) LessThan(other Value) (bool, error) {
+	x, ok := ToFloat(other)
+	if !ok {
+		return false, fmt.Errorf("expected number, but got %s", other.Type())
+	}
+	return float64(v) < x, nil
+}
+
+// ToFloat returns v as float64 if it is VInt or VNumber.
+func ToFloat(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case VInt:
+		return float64(v), true
+	case VNumber:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+type VString string
+
+func (v VString) Type() ValueType {
+	return VTString
+}
+
+func (v VString) String() string {
+	return fmt.Sprintf("\"%s\"", string(v))
+}
+
+func (v VString) Equal(other Value) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	x, ok := other.(VString)
+	if !ok {
+		return false, fmt.Errorf("expected string, but got %s", other.Type())
+	}
+	return string(x) == string(v), nil
+}
+
+func (v VString) LessThan(other Value) (bool, error) {
+	x, ok := other.(VString)
+	if !ok {
+		return false, fmt.Errorf("expected string, but got %s", other.Type())
+	}
+	return string(v) < string(x), nil
+}
+
+type VList struct {
+	Elements []Value
+}
+
+func (v *VList) Type() ValueType {
+	return VTList
+}
+
+func (v *VList) String() string {
+	return v.format(nil)
+}
+
+func (v *VList) format(seen visited) string {
+	if seen[v] {
+		return "[...]"
+	}
+	seen = seen.enter(v)
+	defer delete(seen, v)
+	var elements []string
+	for _, elem := range v.Elements {
+		elements = append(elements, formatIn(elem, seen))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+func (v *VList) Equal(other Value) (bool, error) {
+	return v.equal(other, nil)
+}
+
+func (v *VList) equal(other Value, cmp comparing) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	x, ok := other.(*VList)
+	if !ok {
+		return false, fmt.Errorf("expected list, but got %s", other.Type())
+	}
+	if v == x {
+		return true, nil
+	}
+	if len(v.Elements) != len(x.Elements) {
+		return false, nil
+	}
+	pair := [2]Value{v, x}
+	if cmp[pair] {
+		return true, nil
+	}
+	cmp = cmp.enter(pair)
+	defer delete(cmp, pair)
+	for i, elem := range v.Elements {
+		eq, err := equalIn(elem, x.Elements[i], cmp)
+		if err != nil {
+			return false, err
+		}
+		if !eq {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (v *VList) LessThan(other Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare lists")
+}
+
+type VRecord struct {
+	Fields map[string]Value
+}
+
+func (v *VRecord) Type() ValueType {
+	return VTRecord
+}
+
+func (v *VRecord) String() string {
+	return v.format(nil)
+}
+
+func (v *VRecord) format(seen visited) string {
+	if seen[v] {
+		return "{...}"
+	}
+	seen = seen.enter(v)
+	defer delete(seen, v)
+	var parts []string
+	for _, k := range v.Keys() {
+		parts = append(parts, fmt.Sprintf("%s = %s", k, formatIn(v.Fields[k], seen)))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(parts, ", "))
+}
+
+// Keys returns keys of fields in order, so that iteration is deterministic.
+func (v *VRecord) Keys() []string {
+	keys := make([]string, 0, len(v.Fields))
+	for k := range v.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *VRecord) Equal(other Value) (bool, error) {
+	return v.equal(other, nil)
+}
+
+func (v *VRecord) equal(other Value, cmp comparing) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	o, ok := other.(*VRecord)
+	if !ok {
+		return false, fmt.Errorf("expected record, but got %s", other.Type())
+	}
+	if v == o {
+		return true, nil
+	}
+	if len(o.Fields) != len(v.Fields) {
+		return false, nil
+	}
+	pair := [2]Value{v, o}
+	if cmp[pair] {
+		return true, nil
+	}
+	cmp = cmp.enter(pair)
+	defer delete(cmp, pair)
+	for k, val := range v.Fields {
+		ov, ok := o.Fields[k]
+		if !ok {
+			return false, nil
+		}
+		eq, err := equalIn(val, ov, cmp)
+		if err != nil {
+			return false, err
+		}
+		if !eq {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (v *VRecord) LessThan(other Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare records")
+}
+
+// visited holds lists and records being formatted,
+// so that values containing themselves are formatted as `[...]` or `{...}` where they repeat.
+type visited map[Value]bool
+
+func (seen visited) enter(v Value) visited {
+	if seen == nil {
+		seen = visited{}
+	}
+	seen[v] = true
+	return seen
+}
+
+func formatIn(v Value, seen visited) string {
+	switch v := v.(type) {
+	case *VList:
+		return v.format(seen)
+	case *VRecord:
+		return v.format(seen)
+	}
+	return v.String()
+}
+
+// comparing holds pairs of lists and records being compared,
+// so that values containing themselves are equal if nothing else differs.
+type comparing map[[2]Value]bool
+
+func (cmp comparing) enter(pair [2]Value) comparing {
+	if cmp == nil {
+		cmp = comparing{}
+	}
+	cmp[pair] = true
+	return cmp
+}
+
+func equalIn(a, b Value, cmp comparing) (bool, error) {
+	switch a := a.(type) {
+	case *VList:
+		return a.equal(b, cmp)
+	case *VRecord:
+		return a.equal(b, cmp)
+	}
+	return a.Equal(b)
+}
+
+// Equal compares a and b, regarding values of different types as not equal, except ints and floats.
+func Equal(a Value, b Value) (bool, error) {
+	if a == nil || b == nil {
+		return a == b, nil
+	}
+	_, aNum := ToFloat(a)
+	_, bNum := ToFloat(b)
+	if a.Type() != b.Type() && !(aNum && bNum) {
+		return false, nil
+	}
+	return a.Equal(b)
+}
+
+// ToString converts v to string as string() and string interpolation do.
+func ToString(v Value) (VString, error) {
+	switch v := v.(type) {
+	case VBool:
+		return VString(fmt.Sprintf("%t", v)), nil
+	case VInt:
+		return VString(v.String()), nil
+	case VNumber:
+		return VString(fmt.Sprintf("%g", v)), nil
+	case VString:
+		return v, nil
+	case *VList:
+		return VString(v.String()), nil
+	case *VRecord:
+		return VString(v.String()), nil
+	case VRange:
+		return VString(v.String()), nil
+	case VNil:
+		return VString(v.String()), nil
+	}
+	switch v.Type() {
+	case VTUserFun, VTBuiltinFun:
+		return VString(v.Type().String()), nil
+	case VTError:
+		return VString(v.String()), nil
+	}
+	return "", fmt.Errorf("unknown value type: %s", v.Type().String())
+}
+
+// VRange is numbers from Start up to, but not including, End by Step, made by range().
+// Numbers are computed while iterating instead of being stored.
+type VRange struct {
+	Start, End, Step int
+}
+
+// Len returns the number of numbers in the range.
+func (v VRange) Len() int {
+	return int(v.Count())
+}
+
+// Count returns the number of numbers in the range, which may not fit in int if the range is made by hosts.
+func (v VRange) Count() uint64 {
+	// differences of ints always fit in uint64
+	var diff, step uint64
+	switch {
+	case 0 < v.Step && v.Start < v.End:
+		diff, step = uint64(v.End)-uint64(v.Start), uint64(v.Step)
+	case v.Step < 0 && v.End < v.Start:
+		diff, step = uint64(v.Start)-uint64(v.End), -uint64(v.Step)
+	default:
+		return 0
+	}
+	n := diff / step
+	if diff%step != 0 {
+		n++
+	}
+	return n
+}
+
+// At returns the i-th number of the range.
+// i*Step may overflow for long ranges, but wraps around to the number, which is between Start and End.
+func (v VRange) At(i int) int {
+	return v.Start + i*v.Step
+}
+
+func (v VRange) Type() ValueType {
+	return VTRange
+}
+
+func (v VRange) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", v.Start, v.End, v.Step)
+}
+
+func (v VRange) Equal(other Value) (bool, error) {
+	if _, ok := other.(VNil); ok {
+		return false, nil
+	}
+	o, ok := other.(VRange)
+	if !ok {
+		return false, fmt.Errorf("expected range, but got %s", other.Type())
+	}
+	return o == v, nil
+}
+
+func (v VRange) LessThan(other Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare ranges")
+}
@@ -1,23 +1,25 @@
-package interp
+package vm
 
 import (
 	"fmt"
 	"strings"
+
+	"github.com/fj68/vvlang/value"
 )
 
 type Env struct {
-	Values map[string]Value
+	Values map[string]value.Value
 	outer  *Env
 }
 
 func NewEnv(outer *Env) *Env {
 	return &Env{
-		Values: map[string]Value{},
+		Values: map[string]value.Value{},
 		outer:  outer,
 	}
 }
 
-func (env *Env) Get(name string) (Value, error) {
+func (env *Env) Get(name string) (value.Value, error) {
 	if v, ok := env.Values[name]; ok {
 		return v, nil
 	}
@@ -29,20 +31,20 @@ func (env *Env) Get(name string) (Value, error) {
 	return env.outer.Get(name)
 }
 
-func (env *Env) Set(name string, value Value) {
+func (env *Env) Set(name string, v value.Value) {
 	for e := env.outer; e != nil; e = e.outer {
 		if _, ok := e.Values[name]; ok {
-			e.Values[name] = value
+			e.Values[name] = v
 			return
 		}
 	}
-	env.Values[name] = value
+	env.Values[name] = v
 }
 
 func (env *Env) String() string {
 	var b strings.Builder
-	for name, value := range env.Values {
-		b.WriteString(fmt.Sprintf("%s = %s\n", name, value))
+	for name, v := range env.Values {
+		b.WriteString(fmt.Sprintf("%s = %s\n", name, v))
 	}
 	return b.String()
 }
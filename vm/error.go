@@ -0,0 +1,207 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/parser"
+	"github.com/fj68/vvlang/value"
+)
+
+// RuntimeError is an error raised while evaluating a program,
+// located at the innermost node that failed.
+type RuntimeError struct {
+	Source *lexer.Source
+	Pos    lexer.Pos
+	Err    error
+	// Traceback is the functions running when the error is raised, innermost first.
+	Traceback Traceback
+}
+
+func (err *RuntimeError) Error() string {
+	if err.Source == nil {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s\n%s", err.Source.Locate(err.Pos), err.Err, err.Source.Excerpt(err.Pos))
+}
+
+func (err *RuntimeError) Unwrap() error {
+	return err.Err
+}
+
+// locate attaches pos in src to err, unless err is already located.
+func locate(src *lexer.Source, pos lexer.Pos, err error) error {
+	var rerr *RuntimeError
+	if errors.As(err, &rerr) {
+		return err
+	}
+	// e.g. syntax errors of imported modules
+	var serr *parser.SyntaxError
+	if errors.As(err, &serr) {
+		return err
+	}
+	return &RuntimeError{
+		Source: src,
+		Pos:    pos,
+		Err:    err,
+	}
+}
+
+// TraceFrame is a function running when an error is raised,
+// and the position it is running at, i.e. the error itself or a call to the next inner function.
+type TraceFrame struct {
+	// Name is the name of the function, or "<anonymous>" / "<top level>".
+	Name   string
+	Source *lexer.Source
+	Pos    lexer.Pos
+}
+
+func (f TraceFrame) String() string {
+	if f.Source == nil {
+		return fmt.Sprintf("at %s", f.Name)
+	}
+	return fmt.Sprintf("at %s (%s)", f.Name, f.Source.Locate(f.Pos))
+}
+
+// Traceback is frames of functions running when an error is raised, innermost first.
+type Traceback []TraceFrame
+
+// String returns the frames one per line, folding repetitions of a frame, e.g. by recursion.
+func (tb Traceback) String() string {
+	var b strings.Builder
+	b.WriteString("traceback (most recent call first):")
+	for i := 0; i < len(tb); {
+		n := 1
+		for i+n < len(tb) && tb[i+n] == tb[i] {
+			n++
+		}
+		fmt.Fprintf(&b, "\n  %s", tb[i])
+		if 1 < n {
+			fmt.Fprintf(&b, "\n  ... repeated %d more times", n-1)
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// Kinds of VError.
+const (
+	// ErrorKindThrown is the kind of errors thrown by scripts, unless error() is given another one.
+	ErrorKindThrown = "error"
+	// ErrorKindRuntime is the kind of errors raised by operators and builtins.
+	ErrorKindRuntime = "runtime"
+	// ErrorKindSyntax is the kind of syntax errors of imported modules.
+	ErrorKindSyntax = "syntax"
+)
+
+// VError is an error value, which `throw` raises and `catch` binds.
+// Errors raised by operators and builtins are caught as VError, too.
+type VError struct {
+	Message string
+	Kind    string
+	// Source and Pos locate where the error is raised. Source is nil until it is raised.
+	Source *lexer.Source
+	Pos    lexer.Pos
+	// Err is the Go error the error is caught from, e.g. raised by a builtin, or nil if thrown by scripts.
+	// It is kept to be thrown again, so that hosts can inspect it with errors.Is and errors.As.
+	Err error
+	// traceback is kept to be thrown again, set when the error is caught.
+	traceback Traceback
+}
+
+func (v *VError) Type() value.ValueType {
+	return value.VTError
+}
+
+func (v *VError) String() string {
+	return fmt.Sprintf("%s: %s", v.Kind, v.Message)
+}
+
+func (v *VError) Equal(other value.Value) (bool, error) {
+	return value.Value(v) == other, nil
+}
+
+func (v *VError) LessThan(other value.Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare errors")
+}
+
+// Error makes VError raised as a Go error.
+func (v *VError) Error() string {
+	return v.Message
+}
+
+func (v *VError) Unwrap() error {
+	return v.Err
+}
+
+// field returns fields of the error visible to scripts, e.g. `err.message`.
+func (v *VError) field(name string) (value.Value, bool) {
+	switch name {
+	case "message":
+		return value.VString(v.Message), true
+	case "kind":
+		return value.VString(v.Kind), true
+	case "file", "line", "column":
+		if v.Source == nil {
+			return value.VNil{}, true
+		}
+		line, col := v.Source.LineCol(v.Pos.Start)
+		switch name {
+		case "file":
+			return value.VString(v.Source.Name), true
+		case "line":
+			return value.VInt(line), true
+		}
+		return value.VInt(col), true
+	}
+	return nil, false
+}
+
+// thrown returns the error raised by `throw v` at pc of prog.
+// Errors thrown again keep the position they are raised first.
+func (prog *Program) thrown(pc int, v value.Value) error {
+	var verr *VError
+	switch v := v.(type) {
+	case *VError:
+		verr = v
+	case value.VString:
+		verr = &VError{Message: string(v), Kind: ErrorKindThrown}
+	default:
+		return fmt.Errorf("value of throw is expected error or string, but got %s", v.Type())
+	}
+	if verr.Source == nil {
+		verr.Source, verr.Pos = prog.src, prog.proto.Pos[pc]
+	}
+	return &RuntimeError{Source: verr.Source, Pos: verr.Pos, Err: verr, Traceback: verr.traceback}
+}
+
+// caught converts err into the VError bound by `catch`,
+// or returns nil if err must abort the evaluation, e.g. when a limit is exceeded.
+func caught(err error) *VError {
+	var lerr *LimitError
+	if errors.As(err, &lerr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	var rerr *RuntimeError
+	errors.As(err, &rerr)
+	var verr *VError
+	if errors.As(err, &verr) {
+		if verr.traceback == nil && rerr != nil {
+			verr.traceback = rerr.Traceback
+		}
+		return verr
+	}
+	var serr *parser.SyntaxError
+	if errors.As(err, &serr) {
+		return &VError{Message: serr.Msg, Kind: ErrorKindSyntax, Source: serr.Source, Pos: serr.Pos, Err: serr}
+	}
+	verr = &VError{Message: err.Error(), Kind: ErrorKindRuntime, Err: err}
+	if rerr != nil {
+		verr.Message, verr.Source, verr.Pos, verr.traceback = rerr.Err.Error(), rerr.Source, rerr.Pos, rerr.Traceback
+		verr.Err = rerr.Err
+	}
+	return verr
+}
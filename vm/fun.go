@@ -0,0 +1,33 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/fj68/vvlang/value"
+)
+
+// VUserFun is a function defined by scripts.
+type VUserFun struct {
+	Name string
+	Args []string
+	// program is the compiled body of the function.
+	program *Program
+	// outer is the frame the function was defined in.
+	outer *frame
+}
+
+func (v *VUserFun) Type() value.ValueType {
+	return value.VTUserFun
+}
+
+func (v *VUserFun) String() string {
+	return "fun"
+}
+
+func (v *VUserFun) Equal(other value.Value) (bool, error) {
+	return value.Value(v) == other, nil
+}
+
+func (v *VUserFun) LessThan(other value.Value) (bool, error) {
+	return false, fmt.Errorf("unable to compare functions")
+}
@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/fj68/vvlang/value"
+)
+
+// iterator is an internal value kept on the stack during `for` loops.
+type iterator struct {
+	next func() (key value.Value, value value.Value, ok bool)
+}
+
+func (v *iterator) Type() value.ValueType              { return -1 }
+func (v *iterator) String() string                     { return "iterator" }
+func (v *iterator) Equal(value.Value) (bool, error)    { return false, nil }
+func (v *iterator) LessThan(value.Value) (bool, error) { return false, nil }
+
+// newIterator returns an iterator over v, yielding index and element of lists,
+// index and character of strings, key and field of records in order of keys,
+// and index and number of ranges.
+func newIterator(v value.Value) (value.Value, error) {
+	i := 0
+	switch v := v.(type) {
+	case *value.VList:
+		// see the length every time, so that appending while iterating is safe
+		return &iterator{func() (value.Value, value.Value, bool) {
+			if len(v.Elements) <= i {
+				return nil, nil, false
+			}
+			i++
+			return value.VInt(i - 1), v.Elements[i-1], true
+		}}, nil
+	case value.VString:
+		str, offset := string(v), 0
+		return &iterator{func() (value.Value, value.Value, bool) {
+			if len(str) <= offset {
+				return nil, nil, false
+			}
+			r, size := utf8.DecodeRuneInString(str[offset:])
+			offset += size
+			i++
+			return value.VInt(i - 1), value.VString(r), true
+		}}, nil
+	case *value.VRecord:
+		keys := v.Keys()
+		return &iterator{func() (value.Value, value.Value, bool) {
+			for i < len(keys) {
+				key := keys[i]
+				i++
+				// skip fields deleted while iterating
+				if field, ok := v.Fields[key]; ok {
+					return value.VString(key), field, true
+				}
+			}
+			return nil, nil, false
+		}}, nil
+	case value.VRange:
+		return &iterator{func() (value.Value, value.Value, bool) {
+			if v.Len() <= i {
+				return nil, nil, false
+			}
+			i++
+			return value.VInt(i - 1), value.VInt(v.At(i - 1)), true
+		}}, nil
+	case nil:
+		return nil, fmt.Errorf("unable to iterate nil")
+	}
+	return nil, fmt.Errorf("unable to iterate %s", v.Type())
+}
@@ -0,0 +1,88 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/fj68/vvlang/value"
+)
+
+// checkInterval is the number of steps between checks of the context.
+const checkInterval = 1024
+
+type LimitKind int
+
+const (
+	StepLimit LimitKind = iota
+	CallDepthLimit
+	AllocLimit
+)
+
+func (kind LimitKind) String() string {
+	switch kind {
+	case StepLimit:
+		return "step"
+	case CallDepthLimit:
+		return "call depth"
+	case AllocLimit:
+		return "allocation"
+	}
+	return "unknown"
+}
+
+// LimitError is raised when an evaluation exceeds a limit of the Machine.
+type LimitError struct {
+	Kind LimitKind
+	Max  int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("%s limit exceeded (max %d)", err.Kind, err.Max)
+}
+
+// resetLimits starts counting steps and allocations of a new evaluation.
+func (m *Machine) resetLimits() {
+	m.steps = 0
+	m.nextCheck = 0
+	m.allocs = 0
+}
+
+// checkSteps is called when steps reaches nextCheck.
+func (m *Machine) checkSteps() error {
+	if 0 < m.MaxSteps && m.MaxSteps < m.steps {
+		return &LimitError{StepLimit, m.MaxSteps}
+	}
+	if m.Context != nil {
+		select {
+		case <-m.Context.Done():
+			return m.Context.Err()
+		default:
+		}
+	}
+	m.nextCheck = m.steps + checkInterval
+	if 0 < m.MaxSteps && m.MaxSteps < m.nextCheck {
+		m.nextCheck = m.MaxSteps + 1
+	}
+	return nil
+}
+
+// Alloc counts n units of memory used to construct values.
+func (m *Machine) Alloc(n int) error {
+	m.allocs += n
+	if 0 < m.MaxAllocs && m.MaxAllocs < m.allocs {
+		return &LimitError{AllocLimit, m.MaxAllocs}
+	}
+	return nil
+}
+
+// AllocValue counts memory of newly constructed v, not including its elements.
+func (m *Machine) AllocValue(v value.Value) error {
+	switch v := v.(type) {
+	case value.VString:
+		return m.Alloc(len(v))
+	case *value.VList:
+		return m.Alloc(len(v.Elements) + 1)
+	case *value.VRecord:
+		return m.Alloc(len(v.Fields) + 1)
+	}
+	return nil
+}
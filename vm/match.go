@@ -0,0 +1,84 @@
+package vm
+
+import (
+	"github.com/fj68/vvlang/compiler"
+	"github.com/fj68/vvlang/value"
+)
+
+// match reports whether v matches p, appending values bound by p to bound.
+// exprs holds values of expressions in p.
+func (m *Machine) match(prog *Program, p *compiler.Pattern, v value.Value, exprs []value.Value, bound []value.Value) (bool, []value.Value, error) {
+	switch p.Kind {
+	case compiler.PatValue:
+		eq, err := value.Equal(prog.consts[p.Const], v)
+		return eq, bound, err
+	case compiler.PatExpr:
+		eq, err := value.Equal(exprs[p.Expr], v)
+		return eq, bound, err
+	case compiler.PatWildcard:
+		return true, bound, nil
+	case compiler.PatBind:
+		return true, append(bound, v), nil
+	case compiler.PatList:
+		list, ok := v.(*value.VList)
+		if !ok || len(list.Elements) < len(p.Elems) || (p.Rest == nil && len(list.Elements) != len(p.Elems)) {
+			return false, bound, nil
+		}
+		for i, e := range p.Elems {
+			ok, next, err := m.match(prog, e, list.Elements[i], exprs, bound)
+			if err != nil || !ok {
+				return false, bound, err
+			}
+			bound = next
+		}
+		if p.Rest != nil && p.Rest.Kind == compiler.PatBind {
+			// copy so that modifying the rest does not change the subject
+			rest := make([]value.Value, len(list.Elements)-len(p.Elems))
+			copy(rest, list.Elements[len(p.Elems):])
+			if err := m.Alloc(len(rest) + 1); err != nil {
+				return false, bound, err
+			}
+			bound = append(bound, &value.VList{Elements: rest})
+		}
+		return true, bound, nil
+	case compiler.PatRecord:
+		rec, ok := v.(*value.VRecord)
+		if !ok {
+			return false, bound, nil
+		}
+		for i, e := range p.Elems {
+			field, ok := rec.Fields[p.Keys[i]]
+			if !ok {
+				return false, bound, nil
+			}
+			ok, next, err := m.match(prog, e, field, exprs, bound)
+			if err != nil || !ok {
+				return false, bound, err
+			}
+			bound = next
+		}
+		if p.Rest != nil && p.Rest.Kind == compiler.PatBind {
+			rest := &value.VRecord{Fields: map[string]value.Value{}}
+			for k, field := range rec.Fields {
+				rest.Fields[k] = field
+			}
+			for _, k := range p.Keys {
+				delete(rest.Fields, k)
+			}
+			if err := m.AllocValue(rest); err != nil {
+				return false, bound, err
+			}
+			bound = append(bound, rest)
+		}
+		return true, bound, nil
+	case compiler.PatAny:
+		for _, e := range p.Elems {
+			ok, next, err := m.match(prog, e, v, exprs, bound)
+			if err != nil || ok {
+				return ok, next, err
+			}
+		}
+		return false, bound, nil
+	}
+	return false, bound, nil
+}
@@ -0,0 +1,398 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fj68/vvlang/value"
+)
+
+func expectNumbers(name string, left value.Value, right value.Value) (float64, float64, error) {
+	lvalue, ok := value.ToFloat(left)
+	if !ok {
+		return 0, 0, fmt.Errorf("left side value of %s expression is not a number", name)
+	}
+	rvalue, ok := value.ToFloat(right)
+	if !ok {
+		return 0, 0, fmt.Errorf("right side value of %s expression is not a number", name)
+	}
+	return lvalue, rvalue, nil
+}
+
+// expectInts returns left and right if both are ints, which are computed without promoting to float.
+func expectInts(left value.Value, right value.Value) (int64, int64, bool) {
+	lvalue, ok := left.(value.VInt)
+	if !ok {
+		return 0, 0, false
+	}
+	rvalue, ok := right.(value.VInt)
+	if !ok {
+		return 0, 0, false
+	}
+	return int64(lvalue), int64(rvalue), true
+}
+
+var ErrIntegerOverflow = fmt.Errorf("integer overflow")
+
+func evalAddExpr(left value.Value, right value.Value) (value.Value, error) {
+	if a, b, ok := expectInts(left, right); ok {
+		c := a + b
+		if (0 < b && c < a) || (b < 0 && a < c) {
+			return nil, ErrIntegerOverflow
+		}
+		return value.VInt(c), nil
+	}
+	lvalue, rvalue, err := expectNumbers("add", left, right)
+	if err != nil {
+		return nil, err
+	}
+	return value.VNumber(lvalue + rvalue), nil
+}
+
+func evalSubExpr(left value.Value, right value.Value) (value.Value, error) {
+	if a, b, ok := expectInts(left, right); ok {
+		c := a - b
+		if (0 < b && a < c) || (b < 0 && c < a) {
+			return nil, ErrIntegerOverflow
+		}
+		return value.VInt(c), nil
+	}
+	lvalue, rvalue, err := expectNumbers("sub", left, right)
+	if err != nil {
+		return nil, err
+	}
+	return value.VNumber(lvalue - rvalue), nil
+}
+
+func evalMulExpr(left value.Value, right value.Value) (value.Value, error) {
+	if a, b, ok := expectInts(left, right); ok {
+		c := a * b
+		if a != 0 && (c/a != b || (a == -1 && b == math.MinInt64)) {
+			return nil, ErrIntegerOverflow
+		}
+		return value.VInt(c), nil
+	}
+	lvalue, rvalue, err := expectNumbers("mul", left, right)
+	if err != nil {
+		return nil, err
+	}
+	return value.VNumber(lvalue * rvalue), nil
+}
+
+var ErrDivisionByZero = fmt.Errorf("division by zero")
+
+// evalDivExpr divides always as floats, e.g. 7 / 2 is 3.5. See evalIntDivExpr for `div`.
+func evalDivExpr(left value.Value, right value.Value) (value.Value, error) {
+	lvalue, rvalue, err := expectNumbers("div", left, right)
+	if err != nil {
+		return nil, err
+	}
+	if rvalue == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return value.VNumber(lvalue / rvalue), nil
+}
+
+// evalIntDivExpr divides truncating toward zero, so that `(a div b) * b + a mod b` is a.
+func evalIntDivExpr(left value.Value, right value.Value) (value.Value, error) {
+	if a, b, ok := expectInts(left, right); ok {
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if a == math.MinInt64 && b == -1 {
+			return nil, ErrIntegerOverflow
+		}
+		return value.VInt(a / b), nil
+	}
+	lvalue, rvalue, err := expectNumbers("div", left, right)
+	if err != nil {
+		return nil, err
+	}
+	if rvalue == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return value.VNumber(math.Trunc(lvalue / rvalue)), nil
+}
+
+func evalModExpr(left value.Value, right value.Value) (value.Value, error) {
+	if a, b, ok := expectInts(left, right); ok {
+		if b == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return value.VInt(a % b), nil
+	}
+	lvalue, rvalue, err := expectNumbers("mod", left, right)
+	if err != nil {
+		return nil, err
+	}
+	if rvalue == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return value.VNumber(math.Mod(lvalue, rvalue)), nil
+}
+
+func evalEqualExpr(left value.Value, right value.Value) (value.Value, error) {
+	v, err := left.Equal(right)
+	if err != nil {
+		return nil, err
+	}
+	return value.VBool(v), nil
+}
+
+func evalLessThanExpr(left value.Value, right value.Value) (value.Value, error) {
+	v, err := left.LessThan(right)
+	if err != nil {
+		return nil, err
+	}
+	return value.VBool(v), nil
+}
+
+func evalLessThanEqualExpr(left value.Value, right value.Value) (value.Value, error) {
+	v, err := evalEqualExpr(left, right)
+	if err != nil {
+		return nil, err
+	}
+	if bool(v.(value.VBool)) {
+		return v, nil
+	}
+	return evalLessThanExpr(left, right)
+}
+
+func evalNotEqualExpr(left value.Value, right value.Value) (value.Value, error) {
+	v, err := evalEqualExpr(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return !v.(value.VBool), nil
+}
+
+// evalGreaterThanExpr compares by LessThan of right, after left and right are evaluated in order.
+func evalGreaterThanExpr(left value.Value, right value.Value) (value.Value, error) {
+	return evalLessThanExpr(right, left)
+}
+
+func evalGreaterThanEqualExpr(left value.Value, right value.Value) (value.Value, error) {
+	return evalLessThanEqualExpr(right, left)
+}
+
+func not(v value.Value) (value.Value, error) {
+	b, ok := v.(value.VBool)
+	if !ok {
+		return nil, fmt.Errorf("operand of not is expected bool, but got %s", v.Type())
+	}
+	return !b, nil
+}
+
+func negate(v value.Value) (value.Value, error) {
+	switch num := v.(type) {
+	case value.VInt:
+		if num == math.MinInt64 {
+			return nil, ErrIntegerOverflow
+		}
+		return -num, nil
+	case value.VNumber:
+		return -num, nil
+	}
+	return nil, fmt.Errorf("cannot negate %s", v.Type())
+}
+
+func fieldOf(recordVal value.Value, field string) (value.Value, error) {
+	if e, ok := recordVal.(*VError); ok {
+		if fieldVal, ok := e.field(field); ok {
+			return fieldVal, nil
+		}
+		return nil, fmt.Errorf("error does not have field '%s'", field)
+	}
+	rec, ok := recordVal.(*value.VRecord)
+	if !ok {
+		return nil, fmt.Errorf("cannot access field on non-record value of type %s", recordVal.Type())
+	}
+	fieldVal, ok := rec.Fields[field]
+	if !ok {
+		return nil, fmt.Errorf("record does not have field '%s'", field)
+	}
+	return fieldVal, nil
+}
+
+// optionalFieldOf returns the field of recordVal, or nil if recordVal does not have it.
+// Skipping nil records is up to the code.
+func optionalFieldOf(recordVal value.Value, field string) (value.Value, error) {
+	switch v := recordVal.(type) {
+	case *value.VRecord:
+		if fieldVal, ok := v.Fields[field]; ok {
+			return fieldVal, nil
+		}
+		return value.VNil{}, nil
+	case *VError:
+		if fieldVal, ok := v.field(field); ok {
+			return fieldVal, nil
+		}
+		return value.VNil{}, nil
+	}
+	return nil, fmt.Errorf("cannot access field on non-record value of type %s", recordVal.Type())
+}
+
+// setField assigns v to field of recordVal, counting the field if it is added.
+func (m *Machine) setField(recordVal value.Value, field string, v value.Value) error {
+	rec, ok := recordVal.(*value.VRecord)
+	if !ok {
+		return fmt.Errorf("cannot assign field on non-record value of type %s", recordVal.Type())
+	}
+	if _, ok := rec.Fields[field]; !ok {
+		if err := m.Alloc(1); err != nil {
+			return err
+		}
+	}
+	rec.Fields[field] = v
+	return nil
+}
+
+// indexNumber returns index as int, which must be an int or a float without fraction.
+// what names the index in error messages, e.g. "list index".
+func indexNumber(what string, index value.Value) (int, error) {
+	switch n := index.(type) {
+	case value.VInt:
+		if n < math.MinInt || math.MaxInt < n {
+			return 0, fmt.Errorf("%s out of range: %d", what, n)
+		}
+		return int(n), nil
+	case value.VNumber:
+		f := float64(n)
+		if f != math.Trunc(f) || f < math.MinInt || math.MaxInt <= f {
+			return 0, fmt.Errorf("%s must be an integer, got %g", what, f)
+		}
+		return int(f), nil
+	}
+	return 0, fmt.Errorf("%s must be a number, got %s", what, index.Type())
+}
+
+func indexOf(left value.Value, index value.Value) (value.Value, error) {
+	switch l := left.(type) {
+	case *value.VList:
+		intIdx, err := indexNumber("list index", index)
+		if err != nil {
+			return nil, err
+		}
+		// handle negative indices
+		if intIdx < 0 {
+			intIdx = len(l.Elements) + intIdx
+		}
+		if intIdx < 0 || intIdx >= len(l.Elements) {
+			return nil, fmt.Errorf("list index out of range: %d", intIdx)
+		}
+		return l.Elements[intIdx], nil
+	case value.VString:
+		intIdx, err := indexNumber("string index", index)
+		if err != nil {
+			return nil, err
+		}
+		// index by runes, as len() counts them, and handle negative indices
+		runes := []rune(string(l))
+		if intIdx < 0 {
+			intIdx = len(runes) + intIdx
+		}
+		if intIdx < 0 || intIdx >= len(runes) {
+			return nil, fmt.Errorf("string index out of range: %d", intIdx)
+		}
+		return value.VString(string(runes[intIdx])), nil
+	case *value.VRecord:
+		key, ok := index.(value.VString)
+		if !ok {
+			return nil, fmt.Errorf("record key must be a string, got %s", index.Type())
+		}
+		return fieldOf(l, string(key))
+	default:
+		return nil, fmt.Errorf("cannot index %s", left.Type())
+	}
+}
+
+func (m *Machine) setIndex(left value.Value, index value.Value, v value.Value) error {
+	if _, ok := left.(*value.VRecord); ok {
+		key, ok := index.(value.VString)
+		if !ok {
+			return fmt.Errorf("record key must be a string, got %s", index.Type())
+		}
+		return m.setField(left, string(key), v)
+	}
+	list, ok := left.(*value.VList)
+	if !ok {
+		return fmt.Errorf("cannot assign index on %s", left.Type())
+	}
+	intIdx, err := indexNumber("list index", index)
+	if err != nil {
+		return err
+	}
+	// handle negative indices
+	if intIdx < 0 {
+		intIdx = len(list.Elements) + intIdx
+	}
+	if intIdx < 0 || intIdx >= len(list.Elements) {
+		return fmt.Errorf("list index out of range: %d", intIdx)
+	}
+	list.Elements[intIdx] = v
+	return nil
+}
+
+// sliceOf slices left by start and end, either of which may be nil when omitted.
+func sliceOf(left value.Value, start value.Value, end value.Value) (value.Value, error) {
+	switch l := left.(type) {
+	case *value.VList:
+		from, to, err := sliceRange(len(l.Elements), start, end)
+		if err != nil {
+			return nil, err
+		}
+		// copy so that assigning to the slice does not modify the original list
+		elements := make([]value.Value, to-from)
+		copy(elements, l.Elements[from:to])
+		return &value.VList{Elements: elements}, nil
+	case value.VString:
+		// slice by runes, as len() counts them
+		runes := []rune(string(l))
+		from, to, err := sliceRange(len(runes), start, end)
+		if err != nil {
+			return nil, err
+		}
+		return value.VString(string(runes[from:to])), nil
+	default:
+		return nil, fmt.Errorf("cannot slice %s", left.Type())
+	}
+}
+
+// sliceRange resolves negative bounds of a slice and clamps them to [0, length].
+func sliceRange(length int, start value.Value, end value.Value) (int, int, error) {
+	from, to := 0, length
+
+	if start != nil {
+		n, err := indexNumber("slice start", start)
+		if err != nil {
+			return 0, 0, err
+		}
+		from = n
+		if from < 0 {
+			from = length + from
+		}
+	}
+
+	if end != nil {
+		n, err := indexNumber("slice end", end)
+		if err != nil {
+			return 0, 0, err
+		}
+		to = n
+		if to < 0 {
+			to = length + to
+		}
+	}
+
+	// Clamp to valid range
+	if from < 0 {
+		from = 0
+	}
+	if to > length {
+		to = length
+	}
+	if from > to {
+		from = to
+	}
+	return from, to, nil
+}
@@ -0,0 +1,525 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fj68/vvlang/compiler"
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/value"
+)
+
+// Program is a compiled function ready to run,
+// with its constants converted to values.
+type Program struct {
+	proto  *compiler.Proto
+	consts []value.Value
+	protos []*Program
+	// src is the source the function was compiled from, used to locate errors.
+	src *lexer.Source
+	// globals holds top-level variables of the program or module the function belongs to.
+	globals *Env
+}
+
+// NewProgram prepares proto compiled from src to run, whose globals are in globals.
+func NewProgram(proto *compiler.Proto, src *lexer.Source, globals *Env) *Program {
+	prog := &Program{
+		proto:   proto,
+		src:     src,
+		globals: globals,
+	}
+	for _, c := range proto.Consts {
+		switch c := c.(type) {
+		case nil:
+			prog.consts = append(prog.consts, value.VNil{})
+		case int64:
+			prog.consts = append(prog.consts, value.VInt(c))
+		case float64:
+			prog.consts = append(prog.consts, value.VNumber(c))
+		case string:
+			prog.consts = append(prog.consts, value.VString(c))
+		case bool:
+			prog.consts = append(prog.consts, value.VBool(c))
+		}
+	}
+	for _, p := range proto.Protos {
+		prog.protos = append(prog.protos, NewProgram(p, src, globals))
+	}
+	return prog
+}
+
+// errorAt attaches the position of the instruction at pc to err.
+func (prog *Program) errorAt(pc int, err error) error {
+	return locate(prog.src, prog.proto.Pos[pc], err)
+}
+
+// frame holds local variables of a running function.
+// Closures keep the frame they are created in as outer,
+// so that they can access variables of enclosing functions.
+type frame struct {
+	prog   *Program
+	locals []value.Value
+	outer  *frame
+	// caller is the frame running when the function is called, and pc is where it calls another function.
+	caller *frame
+	pc     int
+}
+
+// undefined fills local slots which are not assigned yet.
+type undefinedValue struct{}
+
+var undefined value.Value = &undefinedValue{}
+
+func (v *undefinedValue) Type() value.ValueType              { return -1 }
+func (v *undefinedValue) String() string                     { return "undefined" }
+func (v *undefinedValue) Equal(value.Value) (bool, error)    { return false, nil }
+func (v *undefinedValue) LessThan(value.Value) (bool, error) { return false, nil }
+
+func newFrame(prog *Program, outer *frame, args []value.Value) *frame {
+	locals := make([]value.Value, len(prog.proto.Locals))
+	n := copy(locals, args)
+	for i := n; i < len(locals); i++ {
+		locals[i] = undefined
+	}
+	return &frame{prog: prog, locals: locals, outer: outer}
+}
+
+// name returns the name of the function of fr shown in tracebacks.
+func (fr *frame) name() string {
+	if fr.prog.proto.Name != "" {
+		return fr.prog.proto.Name
+	}
+	if fr.outer == nil {
+		return "<top level>"
+	}
+	return "<anonymous>"
+}
+
+// traceback returns frames from fr running at pc to the outermost caller.
+func (fr *frame) traceback(pc int) Traceback {
+	var tb Traceback
+	for {
+		tb = append(tb, TraceFrame{Name: fr.name(), Source: fr.prog.src, Pos: fr.prog.proto.Pos[pc]})
+		if fr.caller == nil {
+			return tb
+		}
+		fr, pc = fr.caller, fr.caller.pc
+	}
+}
+
+// traced attaches the traceback of fr at pc to err, unless err is raised by an inner function.
+func (fr *frame) traced(pc int, err error) error {
+	var rerr *RuntimeError
+	if errors.As(err, &rerr) && rerr.Traceback == nil {
+		rerr.Traceback = fr.traceback(pc)
+	}
+	return err
+}
+
+func (fr *frame) load(slot int) (value.Value, error) {
+	v := fr.locals[slot]
+	if v == undefined {
+		return nil, fmt.Errorf("variable named '%s' is not found", fr.prog.proto.Locals[slot])
+	}
+	return v, nil
+}
+
+func (fr *frame) up(depth int) *frame {
+	for ; 0 < depth; depth-- {
+		fr = fr.outer
+	}
+	return fr
+}
+
+// Machine runs programs, counting steps, call depth and allocations against its limits.
+type Machine struct {
+	// CallNative calls fn which is not a VUserFun, e.g. builtins of the host.
+	// Values are not callable if it is nil.
+	CallNative func(fn value.Value, args []value.Value) (value.Value, error)
+	// MaxSteps limits the number of instructions executed by one evaluation.
+	MaxSteps int
+	// MaxCallDepth limits the depth of nested function calls.
+	MaxCallDepth int
+	// MaxAllocs limits the size of lists, records and strings constructed by one evaluation,
+	// counted as the number of elements, fields and bytes.
+	MaxAllocs int
+	// Context aborts the evaluation with its error when it is done, unless it is nil.
+	Context context.Context
+
+	// frame is the frame of the running function.
+	frame     *frame
+	steps     int
+	nextCheck int
+	depth     int
+	allocs    int
+}
+
+// Run runs the top level of prog and returns the value of its `return`, if any.
+func (m *Machine) Run(prog *Program) (value.Value, error) {
+	return m.run(newFrame(prog, nil, nil))
+}
+
+// Call calls fn, which is either a VUserFun or a value called by CallNative, with args.
+func (m *Machine) Call(fn value.Value, args []value.Value) (value.Value, error) {
+	f, ok := fn.(*VUserFun)
+	if !ok {
+		return m.callNative(fn, args)
+	}
+	if len(f.Args) != len(args) {
+		return nil, fmt.Errorf("not enough or too much arguments")
+	}
+	return m.run(newFrame(f.program, f.outer, args))
+}
+
+// callNative calls fn by CallNative, regarding its nil result as VNil.
+func (m *Machine) callNative(fn value.Value, args []value.Value) (value.Value, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("unable to call nil")
+	}
+	if m.CallNative == nil {
+		return nil, fmt.Errorf("unable to call %s", fn.Type())
+	}
+	v, err := m.CallNative(fn, args)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return value.VNil{}, nil
+	}
+	return v, nil
+}
+
+// Source returns the source of the running function, or nil if nothing is running.
+func (m *Machine) Source() *lexer.Source {
+	if m.frame == nil {
+		return nil
+	}
+	return m.frame.prog.src
+}
+
+// run executes the function of fr until it returns.
+func (m *Machine) run(fr *frame) (value.Value, error) {
+	caller := m.frame
+	if caller == nil {
+		// called by the host
+		m.resetLimits()
+	}
+	if 0 < m.MaxCallDepth && m.MaxCallDepth <= m.depth {
+		return nil, &LimitError{CallDepthLimit, m.MaxCallDepth}
+	}
+	fr.caller = caller
+	m.frame = fr
+	m.depth++
+	v, err := m.exec(fr)
+	m.depth--
+	m.frame = caller
+	// closures keep fr as outer, but not its callers
+	fr.caller = nil
+	return v, err
+}
+
+// handler is an active try statement, which continues at pc with the stack of sp values and the error.
+type handler struct {
+	pc int
+	sp int
+}
+
+func (m *Machine) exec(fr *frame) (value.Value, error) {
+	prog := fr.prog
+	proto := prog.proto
+	code := proto.Code
+	stack := make([]value.Value, proto.MaxStack)
+	sp := 0
+	var handlers []handler
+
+	for pc := 0; pc < len(code); {
+		m.steps++
+		if m.nextCheck <= m.steps {
+			if err := m.checkSteps(); err != nil {
+				return nil, fr.traced(pc, prog.errorAt(pc, err))
+			}
+		}
+
+		in := code[pc]
+		pc++
+
+		var err error
+		switch in.Op {
+		case compiler.OpConst:
+			stack[sp] = prog.consts[in.A]
+			sp++
+		case compiler.OpNil:
+			stack[sp] = value.VNil{}
+			sp++
+		case compiler.OpPop:
+			sp--
+			stack[sp] = nil
+		case compiler.OpDup:
+			stack[sp] = stack[sp-1]
+			sp++
+		case compiler.OpLoadLocal:
+			stack[sp], err = fr.load(in.A)
+			sp++
+		case compiler.OpStoreLocal:
+			sp--
+			fr.locals[in.A] = stack[sp]
+		case compiler.OpLoadOuter:
+			stack[sp], err = fr.up(in.A).load(in.B)
+			sp++
+		case compiler.OpStoreOuter:
+			sp--
+			fr.up(in.A).locals[in.B] = stack[sp]
+		case compiler.OpLoadGlobal:
+			stack[sp], err = prog.globals.Get(proto.Names[in.A])
+			sp++
+		case compiler.OpStoreGlobal:
+			sp--
+			// assigning to a global shadows builtins instead of overwriting them
+			prog.globals.Values[proto.Names[in.A]] = stack[sp]
+		case compiler.OpAdd:
+			sp--
+			stack[sp-1], err = evalAddExpr(stack[sp-1], stack[sp])
+		case compiler.OpSub:
+			sp--
+			stack[sp-1], err = evalSubExpr(stack[sp-1], stack[sp])
+		case compiler.OpMul:
+			sp--
+			stack[sp-1], err = evalMulExpr(stack[sp-1], stack[sp])
+		case compiler.OpDiv:
+			sp--
+			stack[sp-1], err = evalDivExpr(stack[sp-1], stack[sp])
+		case compiler.OpMod:
+			sp--
+			stack[sp-1], err = evalModExpr(stack[sp-1], stack[sp])
+		case compiler.OpIntDiv:
+			sp--
+			stack[sp-1], err = evalIntDivExpr(stack[sp-1], stack[sp])
+		case compiler.OpEqual:
+			sp--
+			stack[sp-1], err = evalEqualExpr(stack[sp-1], stack[sp])
+		case compiler.OpLess:
+			sp--
+			stack[sp-1], err = evalLessThanExpr(stack[sp-1], stack[sp])
+		case compiler.OpLessEq:
+			sp--
+			stack[sp-1], err = evalLessThanEqualExpr(stack[sp-1], stack[sp])
+		case compiler.OpNotEqual:
+			sp--
+			stack[sp-1], err = evalNotEqualExpr(stack[sp-1], stack[sp])
+		case compiler.OpGreater:
+			sp--
+			stack[sp-1], err = evalGreaterThanExpr(stack[sp-1], stack[sp])
+		case compiler.OpGreaterEq:
+			sp--
+			stack[sp-1], err = evalGreaterThanEqualExpr(stack[sp-1], stack[sp])
+		case compiler.OpNeg:
+			stack[sp-1], err = negate(stack[sp-1])
+		case compiler.OpNot:
+			stack[sp-1], err = not(stack[sp-1])
+		case compiler.OpJump:
+			pc = in.A
+		case compiler.OpJumpIfFalse:
+			sp--
+			cond, ok := stack[sp].(value.VBool)
+			if !ok {
+				err = fmt.Errorf("expected bool, but got %s", stack[sp].Type())
+			} else if !cond {
+				pc = in.A
+			}
+		case compiler.OpJumpIfFalseOrPop, compiler.OpJumpIfTrueOrPop:
+			cond, ok := stack[sp-1].(value.VBool)
+			if !ok {
+				err = fmt.Errorf("left side of %s expr is expected bool, but got %s", logicalOps[in.B], stack[sp-1].Type())
+			} else if bool(cond) == (in.Op == compiler.OpJumpIfTrueOrPop) {
+				pc = in.A
+			} else {
+				sp--
+			}
+		case compiler.OpJumpIfNil:
+			if _, ok := stack[sp-1].(value.VNil); ok {
+				pc = in.A
+			}
+		case compiler.OpExpectBool:
+			if _, ok := stack[sp-1].(value.VBool); !ok {
+				err = fmt.Errorf("right side of %s expr is expected bool, but got %s", logicalOps[in.A], stack[sp-1].Type())
+			}
+		case compiler.OpIter:
+			stack[sp-1], err = newIterator(stack[sp-1])
+		case compiler.OpNext:
+			key, v, ok := stack[sp-1].(*iterator).next()
+			if !ok {
+				pc = in.A
+				break
+			}
+			if in.B == 1 {
+				stack[sp] = key
+				sp++
+			}
+			stack[sp] = v
+			sp++
+		case compiler.OpMatch:
+			p := proto.Patterns[in.A]
+			n := p.Exprs()
+			sp -= n
+			var ok bool
+			var bound []value.Value
+			ok, bound, err = m.match(prog, p, stack[sp-1], stack[sp:sp+n], nil)
+			if err != nil {
+				break
+			}
+			if !ok {
+				pc = in.B
+				break
+			}
+			sp--
+			sp += copy(stack[sp:], bound)
+		case compiler.OpList:
+			elements := make([]value.Value, in.A)
+			copy(elements, stack[sp-in.A:sp])
+			sp -= in.A
+			stack[sp] = &value.VList{Elements: elements}
+			sp++
+			err = m.Alloc(in.A + 1)
+		case compiler.OpAppend:
+			sp--
+			list := stack[sp-1].(*value.VList)
+			list.Elements = append(list.Elements, stack[sp])
+			err = m.Alloc(1)
+		case compiler.OpExtend:
+			sp--
+			other, ok := stack[sp].(*value.VList)
+			if !ok {
+				err = fmt.Errorf("cannot spread non-list value of type %s", stack[sp].Type())
+				break
+			}
+			list := stack[sp-1].(*value.VList)
+			list.Elements = append(list.Elements, other.Elements...)
+			err = m.Alloc(len(other.Elements))
+		case compiler.OpRecord:
+			stack[sp] = &value.VRecord{Fields: map[string]value.Value{}}
+			sp++
+			err = m.Alloc(1)
+		case compiler.OpSetKey:
+			sp--
+			stack[sp-1].(*value.VRecord).Fields[proto.Names[in.A]] = stack[sp]
+			err = m.Alloc(1)
+		case compiler.OpMerge:
+			sp--
+			other, ok := stack[sp].(*value.VRecord)
+			if !ok {
+				err = fmt.Errorf("cannot spread non-record value of type %s", stack[sp].Type())
+				break
+			}
+			rec := stack[sp-1].(*value.VRecord)
+			for k, v := range other.Fields {
+				rec.Fields[k] = v
+			}
+			err = m.Alloc(len(other.Fields))
+		case compiler.OpField:
+			if in.B == 1 {
+				stack[sp-1], err = optionalFieldOf(stack[sp-1], proto.Names[in.A])
+			} else {
+				stack[sp-1], err = fieldOf(stack[sp-1], proto.Names[in.A])
+			}
+		case compiler.OpSetField:
+			sp -= 2
+			err = m.setField(stack[sp], proto.Names[in.A], stack[sp+1])
+		case compiler.OpIndex:
+			sp--
+			stack[sp-1], err = indexOf(stack[sp-1], stack[sp])
+		case compiler.OpSetIndex:
+			sp -= 3
+			err = m.setIndex(stack[sp], stack[sp+1], stack[sp+2])
+		case compiler.OpSlice:
+			var start, end value.Value
+			if in.A&compiler.SliceEnd != 0 {
+				sp--
+				end = stack[sp]
+			}
+			if in.A&compiler.SliceStart != 0 {
+				sp--
+				start = stack[sp]
+			}
+			stack[sp-1], err = sliceOf(stack[sp-1], start, end)
+			if err == nil {
+				err = m.AllocValue(stack[sp-1])
+			}
+		case compiler.OpConcat:
+			var b strings.Builder
+			for _, v := range stack[sp-in.A : sp] {
+				str, e := value.ToString(v)
+				if e != nil {
+					err = e
+					break
+				}
+				b.WriteString(string(str))
+			}
+			sp -= in.A
+			stack[sp] = value.VString(b.String())
+			sp++
+			if err == nil {
+				err = m.Alloc(b.Len())
+			}
+		case compiler.OpClosure:
+			p := prog.protos[in.A]
+			stack[sp] = &VUserFun{
+				Name:    p.proto.Name,
+				Args:    p.proto.Locals[:p.proto.Params],
+				program: p,
+				outer:   fr,
+			}
+			sp++
+		case compiler.OpCall:
+			base := sp - in.A - 1
+			fr.pc = pc - 1
+			if f, ok := stack[base].(*VUserFun); ok {
+				if len(f.Args) != in.A {
+					err = fmt.Errorf("not enough or too much arguments")
+				} else {
+					stack[base], err = m.run(newFrame(f.program, f.outer, stack[base+1:sp]))
+				}
+			} else {
+				args := make([]value.Value, in.A)
+				copy(args, stack[base+1:sp])
+				stack[base], err = m.callNative(stack[base], args)
+			}
+			for i := base + 1; i < sp; i++ {
+				stack[i] = nil
+			}
+			sp = base + 1
+		case compiler.OpReturn:
+			return stack[sp-1], nil
+		case compiler.OpTry:
+			handlers = append(handlers, handler{in.A, sp})
+		case compiler.OpEndTry:
+			handlers = handlers[:len(handlers)-1]
+		case compiler.OpThrow:
+			sp--
+			err = prog.thrown(pc-1, stack[sp])
+			stack[sp] = nil
+		default:
+			err = fmt.Errorf("unknown instruction: %s", in)
+		}
+		if err != nil {
+			err = fr.traced(pc-1, prog.errorAt(pc-1, err))
+			verr := caught(err)
+			if len(handlers) == 0 || verr == nil {
+				return nil, err
+			}
+			h := handlers[len(handlers)-1]
+			handlers = handlers[:len(handlers)-1]
+			for i := h.sp; i < sp; i++ {
+				stack[i] = nil
+			}
+			sp = h.sp
+			stack[sp] = verr
+			sp++
+			pc = h.pc
+		}
+	}
+	return nil, nil
+}
+
+var logicalOps = [...]string{"and", "or"}
@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fj68/vvlang/compiler"
+	"github.com/fj68/vvlang/lexer"
+	"github.com/fj68/vvlang/parser"
+	"github.com/fj68/vvlang/value"
+)
+
+func load(t *testing.T, text string, globals *Env) *Program {
+	t.Helper()
+	src := lexer.NewSource("", []rune(text))
+	program, err := parser.ParseSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isGlobal := func(name string) bool {
+		_, err := globals.Get(name)
+		return err == nil
+	}
+	proto, err := compiler.Compile(program, isGlobal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProgram(proto, src, globals)
+}
+
+// double is called by CallNative in tests.
+type double struct{}
+
+func (double) Type() value.ValueType              { return value.VTBuiltinFun }
+func (double) String() string                     { return "fun" }
+func (double) Equal(value.Value) (bool, error)    { return false, nil }
+func (double) LessThan(value.Value) (bool, error) { return false, nil }
+
+func callDouble(fn value.Value, args []value.Value) (value.Value, error) {
+	if _, ok := fn.(double); !ok {
+		return nil, fmt.Errorf("unable to call %s", fn.Type())
+	}
+	return value.VInt(2 * args[0].(value.VInt)), nil
+}
+
+func TestMachineRun(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected value.Value
+	}{
+		{"fun f(n) return n * 3 end return f(2)", value.VInt(6)},
+		{"return double(4) + 1", value.VInt(9)},
+		{"xs = [] for x in [1, 2] xs = [...xs, double(x)] end return xs", &value.VList{Elements: []value.Value{value.VInt(2), value.VInt(4)}}},
+		{"try x = 1 div 0 catch e return e.message end", value.VString(ErrDivisionByZero.Error())},
+		{"x = 1 return x", value.VInt(1)},
+	}
+	for _, tt := range tests {
+		globals := NewEnv(nil)
+		globals.Values["double"] = double{}
+		m := &Machine{CallNative: callDouble}
+		v, err := m.Run(load(t, tt.text, globals))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.text, err)
+		}
+		if eq, err := value.Equal(v, tt.expected); err != nil || !eq {
+			t.Fatalf("%s: expected %s, got %s", tt.text, tt.expected, v)
+		}
+	}
+}
+
+func TestMachineCall(t *testing.T) {
+	globals := NewEnv(nil)
+	m := &Machine{}
+	if _, err := m.Run(load(t, "fun add(a, b) return a + b end", globals)); err != nil {
+		t.Fatal(err)
+	}
+	add, err := globals.Get("add")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Call(add, []value.Value{value.VInt(1), value.VInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != value.VInt(3) {
+		t.Fatalf("expected 3, got %s", v)
+	}
+	// without CallNative, only functions of scripts are callable
+	if _, err := m.Call(double{}, []value.Value{value.VInt(1)}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestMachineLimits(t *testing.T) {
+	m := &Machine{MaxSteps: 100}
+	_, err := m.Run(load(t, "while true end", NewEnv(nil)))
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Kind != StepLimit {
+		t.Fatalf("expected step limit, got %v", err)
+	}
+
+	m = &Machine{MaxAllocs: 10}
+	_, err = m.Run(load(t, "xs = [] while true xs = [...xs, 1] end", NewEnv(nil)))
+	if !errors.As(err, &lerr) || lerr.Kind != AllocLimit {
+		t.Fatalf("expected allocation limit, got %v", err)
+	}
+}